@@ -0,0 +1,169 @@
+package gonja_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+func cached(t *testing.T, env *gonja.Environment, name string) (*exec.Template, string) {
+	tpl, err := env.FromCache(name)
+	if !assert.Nil(t, err) {
+		return nil, ""
+	}
+	out, err := tpl.Execute(nil)
+	assert.Nil(t, err)
+	return tpl, out
+}
+
+func reloadTemplates() *loaders.MapLoader {
+	return loaders.NewMapLoader(map[string]string{
+		"page.html":    `{% extends "base.html" %}{% block content %}page{% endblock %}`,
+		"base.html":    `{% include "header.html" %}|{% block content %}{% endblock %}|{% include "footer.html" ignore missing %}`,
+		"header.html":  `header`,
+		"unrelated.md": `unrelated`,
+	})
+}
+
+func TestTemplateDependencies(t *testing.T) {
+	env := tu.NewEnv(reloadTemplates())
+	tpl, err := env.FromFile("page.html")
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"base.html", "header.html", "footer.html"}, tpl.Dependencies)
+	}
+}
+
+func TestAutoReload(t *testing.T) {
+	assert := assert.New(t)
+	loader := reloadTemplates()
+	env := tu.NewEnv(loader)
+	env.AutoReload = true
+
+	first, out := cached(t, env, "page.html")
+	assert.Equal("header|page|", out)
+
+	again, _ := cached(t, env, "page.html")
+	assert.True(first == again, "the template should be cached")
+
+	loader.Set("unrelated.md", "changed")
+	again, _ = cached(t, env, "page.html")
+	assert.True(first == again, "the template should not depend on unrelated.md")
+
+	for _, tc := range []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"page.html", `{% extends "base.html" %}{% block content %}new page{% endblock %}`, "header|new page|"},
+		{"base.html", `{% include "header.html" %}:{% block content %}{% endblock %}:{% include "footer.html" ignore missing %}`, "header:new page:"},
+		{"header.html", `new header`, "new header:new page:"},
+		{"footer.html", `footer`, "new header:new page:footer"},
+	} {
+		loader.Set(tc.name, tc.source)
+		reloaded, out := cached(t, env, "page.html")
+		assert.False(first == reloaded, "changing %s should reload the template", tc.name)
+		assert.Equal(tc.expected, out, "after changing %s", tc.name)
+		first = reloaded
+	}
+}
+
+// racingLoader changes "header.html" right after it has been read once
+type racingLoader struct {
+	*loaders.MapLoader
+	changed bool
+}
+
+func (l *racingLoader) Get(name string) (io.Reader, error) {
+	reader, err := l.MapLoader.Get(name)
+	if name == "header.html" && !l.changed {
+		l.changed = true
+		l.Set("header.html", "changed header")
+	}
+	return reader, err
+}
+
+func TestAutoReloadChangeWhileCompiling(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(&racingLoader{MapLoader: reloadTemplates()})
+	env.AutoReload = true
+
+	first, out := cached(t, env, "page.html")
+	assert.Equal("header|page|", out)
+
+	reloaded, out := cached(t, env, "page.html")
+	assert.False(first == reloaded, "the change while compiling should reload the template")
+	assert.Equal("changed header|page|", out)
+}
+
+func TestAutoReloadDisabled(t *testing.T) {
+	loader := reloadTemplates()
+	env := tu.NewEnv(loader)
+
+	first, _ := cached(t, env, "page.html")
+	loader.Set("header.html", "new header")
+	again, out := cached(t, env, "page.html")
+	assert.True(t, first == again)
+	assert.Equal(t, "header|page|", out)
+}
+
+func TestAutoReloadChoiceLoader(t *testing.T) {
+	assert := assert.New(t)
+	theme := loaders.NewMapLoader(nil)
+	loader := loaders.NewChoiceLoader(theme, reloadTemplates())
+	env := tu.NewEnv(loader)
+	env.AutoReload = true
+
+	first, out := cached(t, env, "page.html")
+	assert.Equal("header|page|", out)
+
+	theme.Set("header.html", "theme header")
+	reloaded, out := cached(t, env, "page.html")
+	assert.False(first == reloaded)
+	assert.Equal("theme header|page|", out)
+	// The outdated template is not a cache hit
+	assert.Equal(gonja.CacheStats{Hits: 0, Misses: 2, Size: 1}, env.Cache.Stats())
+}
+
+func TestAutoReloadFilesystemLoader(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "gonja-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string, mtime time.Time) {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write("page.html", `{% include "header.html" %}|page`, now)
+	write("header.html", `header`, now)
+
+	env := tu.NewEnv(loaders.MustNewFileSystemLoader(dir))
+	env.AutoReload = true
+	first, out := cached(t, env, "page.html")
+	assert.Equal("header|page", out)
+
+	again, _ := cached(t, env, "page.html")
+	assert.True(first == again)
+
+	write("header.html", `HEADER`, now.Add(time.Second))
+	reloaded, out := cached(t, env, "page.html")
+	assert.False(first == reloaded)
+	assert.Equal("HEADER|page", out)
+}
@@ -0,0 +1,78 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+const unsafe = `<a href="x">Tom & 'Jerry'</a>`
+
+var autoescapeByExtensionCases = []struct {
+	filename string
+	expected string
+}{
+	{"page.html", "<p>&lt;a href=&quot;x&quot;&gt;Tom &amp; &#39;Jerry&#39;&lt;/a&gt;</p>\n" +
+		`<script>var value = "\u003Ca href\u003D\"x\"\u003ETom \u0026 \'Jerry\'\u003C/a\u003E";</script>`},
+	{"feed.xml", "<item>&lt;a href=&quot;x&quot;&gt;Tom &amp; &apos;Jerry&apos;&lt;/a&gt;</item>"},
+	{"data.json", `{"value": "\u003ca href=\"x\"\u003eTom \u0026 'Jerry'\u003c/a\u003e"}`},
+	{"notes.txt", unsafe},
+}
+
+func TestAutoescapeByExtension(t *testing.T) {
+	env := tu.NewEnv(loaders.MustNewFileSystemLoader("testData/autoescape"))
+	env.AutoescapeSelector = exec.SelectAutoescape(nil, "")
+	for _, ac := range autoescapeByExtensionCases {
+		test := ac
+		t.Run(test.filename, func(t *testing.T) {
+			out, err := tu.RenderFile(t, env, test.filename, map[string]interface{}{"value": unsafe})
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, out)
+		})
+	}
+	assert.False(t, env.Autoescape, "the environment should not be modified")
+}
+
+func TestAutoescapeUnknownEscaper(t *testing.T) {
+	_, err := tu.Render(t, tu.NewEnv(nil), `{% autoescape "nope" %}{{ value }}{% endautoescape %}`, nil)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `Unknown escaper "nope"`)
+	}
+}
+
+var autoescapeOwnerCases = []struct {
+	name     string
+	expected string
+}{
+	{"child.txt", "<p>&lt;b&gt;</p>|<b>"},
+	{"child.html", "<p>&lt;b&gt;</p>|&lt;b&gt;"},
+	{"super.txt", "<p>&lt;b&gt;</p>|&lt;b&gt;<b>"},
+	{"page.html", "&lt;b&gt;|<b>"},
+	{"page.txt", "&lt;b&gt;|<b>"},
+}
+
+func TestAutoescapeByOwnerTemplate(t *testing.T) {
+	env := tu.NewEnv(loaders.NewMapLoader(map[string]string{
+		"base.html":  `<p>{{ value }}</p>|{% block content %}{{ value }}{% endblock %}`,
+		"child.txt":  `{% extends "base.html" %}{% block content %}{{ value }}{% endblock %}`,
+		"child.html": `{% extends "base.html" %}{% block content %}{{ value }}{% endblock %}`,
+		"super.txt":  `{% extends "base.html" %}{% block content %}{{ super() }}{{ value }}{% endblock %}`,
+		"m.txt":      `{% macro raw(value) %}{{ value }}{% endmacro %}`,
+		"m.html":     `{% macro escaped(value) %}{{ value }}{% endmacro %}`,
+		"page.html":  `{% from "m.html" import escaped %}{% import "m.txt" as m %}{{ escaped(value) }}|{{ m.raw(value) }}`,
+		"page.txt":   `{% from "m.html" import escaped %}{% import "m.txt" as m %}{{ escaped(value) }}|{{ m.raw(value) }}`,
+	}))
+	env.AutoescapeSelector = exec.SelectAutoescape(nil, "")
+	for _, ac := range autoescapeOwnerCases {
+		test := ac
+		t.Run(test.name, func(t *testing.T) {
+			out, err := tu.RenderFile(t, env, test.name, map[string]interface{}{"value": "<b>"})
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, out)
+		})
+	}
+}
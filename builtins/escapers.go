@@ -0,0 +1,54 @@
+package builtins
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/noirbizarre/gonja/exec"
+	u "github.com/noirbizarre/gonja/utils"
+)
+
+// Escapers export all builtin escapers
+var Escapers = exec.EscaperSet{
+	"html": u.Escape,
+	"xml":  escapeXML,
+	"js":   template.JSEscapeString,
+	"css":  escapeCSS,
+	"url":  url.QueryEscape,
+	"json": escapeJSON,
+}
+
+var xmlReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+func escapeXML(in string) string {
+	return xmlReplacer.Replace(in)
+}
+
+// escapeCSS escapes any ASCII non alphanumeric character
+// using the 6 digits form which doesn't require a trailing space
+func escapeCSS(in string) string {
+	var out strings.Builder
+	for _, r := range in {
+		if r >= 0x80 || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			out.WriteRune(r)
+		} else {
+			fmt.Fprintf(&out, `\%06X`, r)
+		}
+	}
+	return out.String()
+}
+
+// escapeJSON escapes in to be used within a JSON string
+func escapeJSON(in string) string {
+	encoded, _ := json.Marshal(in)
+	return string(encoded[1 : len(encoded)-1])
+}
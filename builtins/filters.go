@@ -101,7 +101,7 @@ func filterAttr(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.V
 		return exec.AsValue(errors.Wrap(p, "Wrong signature for 'attr'"))
 	}
 	attr := p.First().String()
-	value, _ := in.Getattr(attr)
+	value, _ := e.Getattr(in, attr)
 	return value
 }
 
@@ -229,6 +229,14 @@ func filterDictSort(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *ex
 		return exec.AsValue(errors.Wrap(p, "Wrong signature for 'dictsort'"))
 	}
 
+	// Items are compared by their string representation
+	if err := e.CheckIterate(in); err != nil {
+		return exec.AsValue(err)
+	}
+	if err := e.CheckString(in, nil); err != nil {
+		return exec.AsValue(err)
+	}
+
 	caseSensitive := p.KwArgs["case_sensitive"].Bool()
 	by := p.KwArgs["by"].String()
 	reverse := p.KwArgs["reverse"].Bool()
@@ -332,9 +340,14 @@ func filterGroupBy(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exe
 	field := p.First().String()
 	groups := map[interface{}][]*exec.Value{}
 	groupers := []interface{}{}
+	var err *exec.Value
 
 	in.Iterate(func(idx, count int, key, value *exec.Value) bool {
-		attr, found := key.Get(field)
+		attr, found := e.Get(key, field)
+		if attr.IsError() {
+			err = attr
+			return false
+		}
 		if !found {
 			return true
 		}
@@ -347,6 +360,9 @@ func filterGroupBy(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exe
 		groups[attr.Interface()] = lst
 		return true
 	}, func() {})
+	if err != nil {
+		return err
+	}
 
 	out := []map[string]*exec.Value{}
 	for _, grouper := range groupers {
@@ -471,10 +487,15 @@ func filterMap(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.Va
 	attribute := p.KwArgs["attribute"].String()
 	defaultVal := p.KwArgs["default"]
 	out := []*exec.Value{}
+	var err *exec.Value
 	in.Iterate(func(idx, count int, key, value *exec.Value) bool {
 		val := key
 		if len(attribute) > 0 {
-			attr, found := val.Get(attribute)
+			attr, found := e.Get(val, attribute)
+			if attr.IsError() {
+				err = attr
+				return false
+			}
 			if found {
 				val = attr
 			} else if defaultVal != nil {
@@ -489,6 +510,9 @@ func filterMap(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.Va
 		out = append(out, val)
 		return true
 	}, func() {})
+	if err != nil {
+		return err
+	}
 	return exec.AsValue(out)
 }
 
@@ -507,7 +531,11 @@ func filterMax(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.Va
 	in.Iterate(func(idx, count int, key, value *exec.Value) bool {
 		val := key
 		if len(attribute) > 0 {
-			attr, found := val.Get(attribute)
+			attr, found := e.Get(val, attribute)
+			if attr.IsError() {
+				max = attr
+				return false
+			}
 			if found {
 				val = attr
 			} else {
@@ -559,7 +587,11 @@ func filterMin(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.Va
 	in.Iterate(func(idx, count int, key, value *exec.Value) bool {
 		val := key
 		if len(attribute) > 0 {
-			attr, found := val.Get(attribute)
+			attr, found := e.Get(val, attribute)
+			if attr.IsError() {
+				min = attr
+				return false
+			}
 			if found {
 				val = attr
 			} else {
@@ -601,6 +633,9 @@ func filterPPrint(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec
 	if p.IsError() {
 		return exec.AsValue(errors.Wrap(p, "Wrong signature for 'pprint'"))
 	}
+	if err := e.CheckSerializable(in); err != nil {
+		return exec.AsValue(err)
+	}
 	b, err := json.MarshalIndent(in.Interface(), "", "  ")
 	if err != nil {
 		return exec.AsValue(errors.Wrapf(err, `Unable to pretty print '%s'`, in.String()))
@@ -659,7 +694,10 @@ func filterRejectAttr(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *
 	if len(params.Args) == 1 {
 		// Reject truthy value
 		test = func(in *exec.Value) *exec.Value {
-			attr, found := in.Get(attribute)
+			attr, found := e.Get(in, attribute)
+			if attr.IsError() {
+				return attr
+			}
 			if !found {
 				return exec.AsValue(errors.Errorf(`%s has no attribute '%s'`, in.String(), attribute))
 			}
@@ -672,7 +710,10 @@ func filterRejectAttr(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *
 			KwArgs: params.KwArgs,
 		}
 		test = func(in *exec.Value) *exec.Value {
-			attr, found := in.Get(attribute)
+			attr, found := e.Get(in, attribute)
+			if attr.IsError() {
+				return attr
+			}
 			if !found {
 				return exec.AsValue(errors.Errorf(`%s has no attribute '%s'`, in.String(), attribute))
 			}
@@ -813,7 +854,10 @@ func filterSelectAttr(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *
 	if len(params.Args) == 1 {
 		// Reject truthy value
 		test = func(in *exec.Value) *exec.Value {
-			attr, found := in.Get(attribute)
+			attr, found := e.Get(in, attribute)
+			if attr.IsError() {
+				return attr
+			}
 			if !found {
 				return exec.AsValue(errors.Errorf(`%s has no attribute '%s'`, in.String(), attribute))
 			}
@@ -826,7 +870,10 @@ func filterSelectAttr(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *
 			KwArgs: params.KwArgs,
 		}
 		test = func(in *exec.Value) *exec.Value {
-			attr, found := in.Get(attribute)
+			attr, found := e.Get(in, attribute)
+			if attr.IsError() {
+				return attr
+			}
 			if !found {
 				return exec.AsValue(errors.Errorf(`%s has no attribute '%s'`, in.String(), attribute))
 			}
@@ -886,6 +933,13 @@ func filterSort(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.V
 	if p.IsError() {
 		return exec.AsValue(errors.Wrap(p, "Wrong signature for 'sort'"))
 	}
+	// Items are compared by their string representation
+	if err := e.CheckIterate(in); err != nil {
+		return exec.AsValue(err)
+	}
+	if err := e.CheckString(in, nil); err != nil {
+		return exec.AsValue(err)
+	}
 	reverse := p.KwArgs["reverse"].Bool()
 	caseSensitive := p.KwArgs["case_sensitive"].Bool()
 	out := []*exec.Value{}
@@ -900,6 +954,9 @@ func filterString(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec
 	if p := params.ExpectNothing(); p.IsError() {
 		return exec.AsValue(errors.Wrap(p, "Wrong signature for 'string'"))
 	}
+	if err := e.CheckString(in, nil); err != nil {
+		return exec.AsValue(err)
+	}
 	return exec.AsValue(in.String())
 }
 
@@ -932,7 +989,11 @@ func filterSum(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.Va
 			val := key
 			found := true
 			for _, attr := range strings.Split(attribute.String(), ".") {
-				val, found = val.Get(attr)
+				val, found = e.Get(val, attr)
+				if val.IsError() {
+					err = val
+					return false
+				}
 				if !found {
 					err = errors.Errorf("'%s' has no attribute '%s'", key.String(), attribute.String())
 					return false
@@ -982,6 +1043,9 @@ func filterToJSON(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec
 	if p.IsError() {
 		return exec.AsValue(errors.Wrap(p, "Wrong signature for 'tojson'"))
 	}
+	if err := e.CheckSerializable(in); err != nil {
+		return exec.AsValue(err)
+	}
 
 	indent := p.KwArgs["indent"]
 	var out string
@@ -1058,7 +1122,11 @@ func filterUnique(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec
 		val := key
 		if attribute.IsString() {
 			attr := attribute.String()
-			nested, found := key.Get(attr)
+			nested, found := e.Get(key, attr)
+			if nested.IsError() {
+				err = nested
+				return false
+			}
 			if !found {
 				err = errors.Errorf(`%s has no attribute %s`, key.String(), attr)
 				return false
@@ -1,6 +1,8 @@
 package builtins
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 
 	"github.com/noirbizarre/gonja/exec"
@@ -13,10 +15,16 @@ var Globals = exec.NewContext(map[string]interface{}{
 	"joiner":    Joiner,
 	"lipsum":    Lipsum,
 	"namespace": Namespace,
-	"range":     Range,
+	"range":     rangeContext,
 })
 
 func Range(va *exec.VarArgs) <-chan int {
+	return rangeContext(context.Background(), va)
+}
+
+// rangeContext is the range global bound to the rendering context:
+// it stops feeding the channel when the rendering is over.
+func rangeContext(ctx context.Context, va *exec.VarArgs) <-chan int {
 	var (
 		start = 0
 		stop  = -1
@@ -37,12 +45,16 @@ func Range(va *exec.VarArgs) <-chan int {
 	}
 	chnl := make(chan int)
 	go func() {
+		// Ensure that at the end of the loop we close the channel!
+		defer close(chnl)
 		for i := start; i < stop; i += step {
-			chnl <- i
+			select {
+			case chnl <- i:
+			case <-ctx.Done():
+				// The rendering is over, stop feeding the loop
+				return
+			}
 		}
-
-		// Ensure that at the end of the loop we close the channel!
-		close(chnl)
 	}()
 	return chnl
 }
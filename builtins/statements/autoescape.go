@@ -3,6 +3,8 @@ package statements
 import (
 	"fmt"
 
+	"github.com/pkg/errors"
+
 	"github.com/noirbizarre/gonja/exec"
 	"github.com/noirbizarre/gonja/nodes"
 	"github.com/noirbizarre/gonja/parser"
@@ -12,6 +14,7 @@ import (
 type AutoescapeStmt struct {
 	Wrapper    *nodes.Wrapper
 	Autoescape bool
+	Escaper    string // The escaper name, if given
 }
 
 func (stmt *AutoescapeStmt) Position() *tokens.Token { return stmt.Wrapper.Position() }
@@ -23,6 +26,12 @@ func (stmt *AutoescapeStmt) String() string {
 func (stmt *AutoescapeStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
 	sub := r.Inherit()
 	sub.Autoescape = stmt.Autoescape
+	if stmt.Escaper != "" {
+		if !sub.Escapers.Exists(stmt.Escaper) {
+			return errors.Errorf(`Unknown escaper "%s"`, stmt.Escaper)
+		}
+		sub.Escaper = stmt.Escaper
+	}
 
 	err := sub.ExecuteWrapper(stmt.Wrapper)
 	if err != nil {
@@ -41,16 +50,20 @@ func autoescapeParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, e
 	}
 	stmt.Wrapper = wrapper
 
-	modeToken := args.Match(tokens.Name)
+	modeToken := args.Match(tokens.Name, tokens.String)
 	if modeToken == nil {
 		return nil, args.Error("A mode is required for autoescape statement.", nil)
 	}
-	if modeToken.Val == "true" {
+	if modeToken.Type == tokens.String {
+		// The escaper name, ie. "js"
+		stmt.Autoescape = true
+		stmt.Escaper = modeToken.Val
+	} else if modeToken.Val == "true" {
 		stmt.Autoescape = true
 	} else if modeToken.Val == "false" {
 		stmt.Autoescape = false
 	} else {
-		return nil, args.Error("Only 'true' or 'false' is valid as an autoescape statement.", nil)
+		return nil, args.Error("Only 'true', 'false' or an escaper name is valid as an autoescape statement.", nil)
 	}
 
 	if !args.Stream.End() {
@@ -32,7 +32,14 @@ func (stmt *BlockStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) erro
 		return errors.Errorf(`Unable to find block "%s"`, stmt.Name)
 	}
 
+	owner := blockOwner(r.Root, stmt.Name, block)
+	if err := r.Enter("block", stmt.Name, owner.Name, stmt.Location); err != nil {
+		return err
+	}
+	defer r.Leave()
+
 	sub := r.Inherit()
+	sub.AutoescapeTemplate(owner)
 	infos := &BlockInfos{Block: stmt, Renderer: sub, Blocks: blocks}
 
 	sub.Ctx.Set("super", infos.super)
@@ -46,6 +53,16 @@ func (stmt *BlockStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) erro
 	return nil
 }
 
+// blockOwner returns the template defining block
+func blockOwner(root *nodes.Template, name string, block *nodes.Wrapper) *nodes.Template {
+	for tpl := root; tpl != nil; tpl = tpl.Parent {
+		if tpl.Blocks[name] == block {
+			return tpl
+		}
+	}
+	return root
+}
+
 type BlockInfos struct {
 	Block    *BlockStmt
 	Renderer *exec.Renderer
@@ -60,6 +77,7 @@ func (bi *BlockInfos) super() string {
 	r := bi.Renderer
 	block, blocks := bi.Blocks[0], bi.Blocks[1:]
 	sub := r.Inherit()
+	sub.AutoescapeTemplate(blockOwner(r.Root, bi.Block.Name, block))
 	var out strings.Builder
 	sub.Out = &out
 	infos := &BlockInfos{
@@ -0,0 +1,114 @@
+package statements
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/nodes"
+	"github.com/noirbizarre/gonja/parser"
+	"github.com/noirbizarre/gonja/tokens"
+)
+
+type CallStmt struct {
+	Location *tokens.Token
+	Call     *nodes.Call
+	Caller   *nodes.Macro
+}
+
+func (stmt *CallStmt) Position() *tokens.Token { return stmt.Location }
+func (stmt *CallStmt) String() string {
+	t := stmt.Position()
+	return fmt.Sprintf("CallStmt(Call=%s Line=%d Col=%d)", stmt.Call, t.Line, t.Col)
+}
+
+func (stmt *CallStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
+	// The caller body is rendered within the current context
+	caller, err := exec.MacroNodeToFunc(stmt.Caller, r)
+	if err != nil {
+		return errors.Wrap(err, `Unable to parse caller`)
+	}
+
+	// Give the caller to the macro as the "caller" keyword argument
+	sub := r.Inherit()
+	sub.Ctx.Set("caller", caller)
+	kwargs := map[string]nodes.Expression{
+		"caller": &nodes.Name{Name: &tokens.Token{
+			Type: tokens.Name,
+			Val:  "caller",
+			Pos:  stmt.Location.Pos,
+			Line: stmt.Location.Line,
+			Col:  stmt.Location.Col,
+		}},
+	}
+	for key, value := range stmt.Call.Kwargs {
+		kwargs[key] = value
+	}
+	call := &nodes.Call{
+		Location: stmt.Call.Location,
+		Func:     stmt.Call.Func,
+		Args:     stmt.Call.Args,
+		Kwargs:   kwargs,
+	}
+
+	value := sub.Eval(call)
+	if value.IsError() {
+		return errors.Wrapf(value, `Unable to execute call '%s'`, stmt.Call)
+	}
+	r.RenderValue(value)
+	return nil
+}
+
+func callParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
+	stmt := &CallStmt{
+		Location: p.Current(),
+		Caller: &nodes.Macro{
+			Location: p.Current(),
+			Name:     "caller",
+			Args:     []string{},
+			Kwargs:   []*nodes.Pair{},
+		},
+	}
+
+	// Optional caller signature
+	if args.Match(tokens.Lparen) != nil {
+		if err := parseMacroSignature(args, stmt.Caller); err != nil {
+			return nil, err
+		}
+	}
+
+	expr, err := args.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	call, ok := expr.(*nodes.Call)
+	if !ok {
+		return nil, args.Error("Expected a macro call.", expr.Position())
+	}
+	stmt.Call = call
+
+	if !args.End() {
+		return nil, args.Error("Malformed call-tag.", nil)
+	}
+
+	// Body wrapping, loop controls can't reach an enclosing loop from there
+	loops := p.Loops
+	p.Loops = 0
+	wrapper, endargs, err := p.WrapUntil("endcall")
+	p.Loops = loops
+	if err != nil {
+		return nil, err
+	}
+	stmt.Caller.Wrapper = wrapper
+
+	if !endargs.End() {
+		return nil, endargs.Error("Arguments not allowed here.", nil)
+	}
+
+	return stmt, nil
+}
+
+func init() {
+	All.Register("call", callParser)
+}
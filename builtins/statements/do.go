@@ -0,0 +1,58 @@
+package statements
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/nodes"
+	"github.com/noirbizarre/gonja/parser"
+	"github.com/noirbizarre/gonja/tokens"
+)
+
+// DoStmt evaluates an expression for its side effects and discards the result
+type DoStmt struct {
+	Location   *tokens.Token
+	Expression nodes.Expression
+}
+
+func (stmt *DoStmt) Position() *tokens.Token { return stmt.Location }
+func (stmt *DoStmt) String() string {
+	t := stmt.Position()
+	return fmt.Sprintf("DoStmt(Line=%d Col=%d)", t.Line, t.Col)
+}
+
+func (stmt *DoStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
+	value := r.Eval(stmt.Expression)
+	if value.IsError() {
+		return errors.Wrapf(value, `Unable to evaluate %s`, stmt.Expression)
+	}
+	return nil
+}
+
+func doParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
+	stmt := &DoStmt{
+		Location: p.Current(),
+	}
+
+	if args.End() {
+		return nil, args.Error("An expression is required for 'do' statement.", args.Current())
+	}
+
+	expr, err := args.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Expression = expr
+
+	if !args.End() {
+		return nil, args.Error("Malformed 'do'-tag args.", args.Current())
+	}
+
+	return stmt, nil
+}
+
+func init() {
+	All.Register("do", doParser)
+}
@@ -42,7 +42,7 @@ func extendsParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, erro
 
 	// var filename nodes.Node
 	if filename := args.Match(tokens.String); filename != nil {
-		stmt.Filename = filename.Val
+		stmt.Filename = p.ResolveTemplate(filename.Val)
 		tpl, err := p.TemplateParser(stmt.Filename)
 		if err != nil {
 			return nil, errors.Wrapf(err, `Unable to parse parent template '%s'`, stmt.Filename)
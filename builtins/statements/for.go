@@ -3,6 +3,9 @@ package statements
 import (
 	"fmt"
 	"math"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"github.com/noirbizarre/gonja/exec"
 	"github.com/noirbizarre/gonja/nodes"
@@ -15,6 +18,7 @@ type ForStmt struct {
 	value           string // only for maps: for key, value in map
 	objectEvaluator nodes.Expression
 	ifCondition     nodes.Expression
+	recursive       bool
 
 	bodyWrapper  *nodes.Wrapper
 	emptyWrapper *nodes.Wrapper
@@ -39,6 +43,20 @@ type LoopInfos struct {
 	PrevItem   *exec.Value
 	NextItem   *exec.Value
 	_lastValue *exec.Value
+	recurse    func(items *exec.Value) *exec.Value
+}
+
+// Call renders the loop body for the given items one level deeper.
+// Only recursive loops can be called: {{ loop(item.children) }}
+func (li *LoopInfos) Call(va *exec.VarArgs) *exec.Value {
+	if li.recurse == nil {
+		return exec.AsValue(errors.New(`Only recursive loops can be called`))
+	}
+	p := va.ExpectArgs(1)
+	if p.IsError() {
+		return exec.AsValue(errors.Wrap(p, `Wrong signature for 'loop'`))
+	}
+	return li.recurse(p.First())
 }
 
 func (li *LoopInfos) Cycle(va *exec.VarArgs) *exec.Value {
@@ -51,17 +69,28 @@ func (li *LoopInfos) Changed(value *exec.Value) bool {
 	return !same
 }
 
-func (node *ForStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) (forError error) {
+func (node *ForStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
 	obj := r.Eval(node.objectEvaluator)
 	if obj.IsError() {
 		return obj
 	}
+	if err := r.Evaluator().CheckIterate(obj); err != nil {
+		return err
+	}
+	return node.render(r, tag, obj, 1)
+}
 
+// render renders the loop over obj at the given depth
+func (node *ForStmt) render(r *exec.Renderer, tag *nodes.StatementBlock, obj *exec.Value, depth int) (forError error) {
 	// Create loop struct
 	items := exec.NewDict()
 
 	// First iteration: filter values to ensure proper LoopInfos
 	obj.Iterate(func(idx, count int, key, value *exec.Value) bool {
+		if err := r.Interrupted(); err != nil {
+			forError = err
+			return false
+		}
 		sub := r.Inherit()
 		ctx := sub.Ctx
 		pair := &exec.Pair{}
@@ -90,30 +119,65 @@ func (node *ForStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) (forEr
 		}
 
 		if node.ifCondition != nil {
-			if !sub.Eval(node.ifCondition).IsTrue() {
+			isTrue, err := sub.Evaluator().IsTrue(sub.Eval(node.ifCondition))
+			if err != nil {
+				forError = err
+				return false
+			}
+			if !isTrue {
 				return true
 			}
 		}
 		items.Pairs = append(items.Pairs, pair)
 		return true
-	}, func() {
-		// Nothing to iterate over (maybe wrong type or no items)
+	}, func() {})
+	if forError != nil {
+		return forError
+	}
+
+	// Nothing to iterate over (maybe wrong type, no items or all filtered out)
+	length := len(items.Pairs)
+	if length == 0 {
 		if node.emptyWrapper != nil {
-			sub := r.Inherit()
-			err := sub.ExecuteWrapper(node.emptyWrapper)
-			if err != nil {
-				forError = err
-			}
+			return r.Inherit().ExecuteWrapper(node.emptyWrapper)
 		}
-	})
+		return nil
+	}
 
 	// 2nd pass: all values are defined, render
-	length := len(items.Pairs)
 	loop := &LoopInfos{
 		first:  true,
 		index0: -1,
+		length: length,
+		depth:  depth,
+		depth0: depth - 1,
+	}
+	if node.recursive {
+		body := r.CurrentTemplate()
+		loop.recurse = func(items *exec.Value) *exec.Value {
+			if err := r.Enter("loop", "loop", body, tag.Location); err != nil {
+				return exec.AsValue(err)
+			}
+			defer r.Leave()
+			// Nested loops are rendered apart, with their own trimming
+			var out strings.Builder
+			sub := r.Inherit()
+			sub.Out = &out
+			sub.Trim = &exec.TrimState{Buffer: &strings.Builder{}}
+			if err := node.render(sub, tag, items, depth+1); err != nil {
+				return exec.AsValue(errors.Wrap(err, `Unable to render recursive loop`))
+			}
+			return exec.AsSafeValue(out.String())
+		}
 	}
 	for idx, pair := range items.Pairs {
+		if err := r.Interrupted(); err != nil {
+			return err
+		}
+		// Only rendered iterations count against the limit
+		if err := r.Iterate(); err != nil {
+			return err
+		}
 		r.EndTag(tag.Trim)
 		sub := r.Inherit()
 		ctx := sub.Ctx
@@ -160,7 +224,12 @@ func (node *ForStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) (forEr
 		// Render elements with updated context
 		err := sub.ExecuteWrapper(node.bodyWrapper)
 		if err != nil {
-			return err
+			if errors.Cause(err) == exec.ErrBreak {
+				break
+			}
+			if errors.Cause(err) != exec.ErrContinue {
+				return err
+			}
 		}
 	}
 
@@ -189,7 +258,7 @@ func forParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
 		return nil, args.Error("Expected keyword 'in'.", nil)
 	}
 
-	objectEvaluator, err := args.ParseExpression()
+	objectEvaluator, err := args.ParseExpressionWithoutCondition()
 	if err != nil {
 		return nil, err
 	}
@@ -207,12 +276,18 @@ func forParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
 		stmt.ifCondition = ifCondition
 	}
 
+	if args.MatchName("recursive") != nil {
+		stmt.recursive = true
+	}
+
 	if !args.End() {
 		return nil, args.Error("Malformed for-loop args.", nil)
 	}
 
 	// Body wrapping
+	p.Loops++
 	wrapper, endargs, err := p.WrapUntil("else", "endfor")
+	p.Loops--
 	if err != nil {
 		return nil, err
 	}
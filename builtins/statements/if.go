@@ -30,7 +30,11 @@ func (node *IfStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
 			return result
 		}
 
-		if result.IsTrue() {
+		isTrue, err := r.Evaluator().IsTrue(result)
+		if err != nil {
+			return err
+		}
+		if isTrue {
 			return r.ExecuteWrapper(node.wrappers[i])
 		}
 		// Last condition?
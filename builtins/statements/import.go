@@ -27,6 +27,7 @@ func (stmt *ImportStmt) String() string {
 }
 func (stmt *ImportStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
 	var imported map[string]*nodes.Macro
+	var owner *nodes.Template
 	macros := map[string]exec.Macro{}
 
 	if stmt.FilenameExpr != nil {
@@ -35,19 +36,28 @@ func (stmt *ImportStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) err
 			return errors.Wrap(filenameValue, `Unable to evaluate filename`)
 		}
 
-		filename := filenameValue.String()
+		filename := r.ResolveTemplate(filenameValue.String())
 		tpl, err := r.Loader.GetTemplate(filename)
 		if err != nil {
 			return errors.Wrapf(err, `Unable to load template '%s'`, filename)
 		}
 		imported = tpl.Root.Macros
+		owner = tpl.Root
+		if err := r.Enter("import", filename, filename, stmt.Location); err != nil {
+			return err
+		}
 
 	} else {
 		imported = stmt.Template.Macros
+		owner = stmt.Template
+		if err := r.Enter("import", stmt.Filename, stmt.Filename, stmt.Location); err != nil {
+			return err
+		}
 	}
+	defer r.Leave()
 
 	for name, macro := range imported {
-		fn, err := exec.MacroNodeToFunc(macro, r)
+		fn, err := exec.MacroNodeToFunc(macro, r.ForTemplate(owner))
 		if err != nil {
 			return errors.Wrapf(err, `Unable to import macro '%s'`, name)
 		}
@@ -75,6 +85,7 @@ func (stmt *FromImportStmt) String() string {
 }
 func (stmt *FromImportStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
 	var imported map[string]*nodes.Macro
+	var owner *nodes.Template
 
 	if stmt.FilenameExpr != nil {
 		filenameValue := r.Eval(stmt.FilenameExpr)
@@ -82,20 +93,29 @@ func (stmt *FromImportStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock)
 			return errors.Wrap(filenameValue, `Unable to evaluate filename`)
 		}
 
-		filename := filenameValue.String()
+		filename := r.ResolveTemplate(filenameValue.String())
 		tpl, err := r.Loader.GetTemplate(filename)
 		if err != nil {
 			return errors.Wrapf(err, `Unable to load template '%s'`, filename)
 		}
 		imported = tpl.Root.Macros
+		owner = tpl.Root
+		if err := r.Enter("import", filename, filename, stmt.Location); err != nil {
+			return err
+		}
 
 	} else {
 		imported = stmt.Template.Macros
+		owner = stmt.Template
+		if err := r.Enter("import", stmt.Filename, stmt.Filename, stmt.Location); err != nil {
+			return err
+		}
 	}
+	defer r.Leave()
 
 	for alias, name := range stmt.As {
 		node := imported[name]
-		fn, err := exec.MacroNodeToFunc(node, r)
+		fn, err := exec.MacroNodeToFunc(node, r.ForTemplate(owner))
 		if err != nil {
 			return errors.Wrapf(err, `Unable to import macro '%s'`, name)
 		}
@@ -143,6 +163,7 @@ func importParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error
 
 	// Preload static template
 	if stmt.Filename != "" {
+		stmt.Filename = p.ResolveTemplate(stmt.Filename)
 		tpl, err := p.TemplateParser(stmt.Filename)
 		if err != nil {
 			return nil, errors.Wrapf(err, `Unable to parse imported template '%s'`, stmt.Filename)
@@ -224,6 +245,7 @@ func fromParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error)
 
 	// Preload static template
 	if stmt.Filename != "" {
+		stmt.Filename = p.ResolveTemplate(stmt.Filename)
 		tpl, err := p.TemplateParser(stmt.Filename)
 		if err != nil {
 			return nil, errors.Wrapf(err, `Unable to parse imported template '%s'`, stmt.Filename)
@@ -40,7 +40,11 @@ func (stmt *IncludeStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) er
 			return errors.Wrap(filenameValue, `Unable to evaluate filename`)
 		}
 
-		filename := filenameValue.String()
+		filename := r.ResolveTemplate(filenameValue.String())
+		if err := r.Enter("include", filename, filename, stmt.Location); err != nil {
+			return errors.Wrapf(err, `Unable to include template '%s'`, filename)
+		}
+		defer r.Leave()
 		included, err := r.Loader.GetTemplate(filename)
 		if err != nil {
 			if stmt.IgnoreMissing {
@@ -53,6 +57,10 @@ func (stmt *IncludeStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) er
 		sub.Root = included.Root
 
 	} else {
+		if err := r.Enter("include", stmt.Filename, stmt.Filename, stmt.Location); err != nil {
+			return errors.Wrapf(err, `Unable to include template '%s'`, stmt.Filename)
+		}
+		defer r.Leave()
 		sub.Root = stmt.Template
 	}
 
@@ -98,6 +106,7 @@ func includeParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, erro
 
 	// Preload static template
 	if stmt.Filename != "" {
+		stmt.Filename = p.ResolveTemplate(stmt.Filename)
 		tpl, err := p.TemplateParser(stmt.Filename)
 		if err != nil {
 			if stmt.IgnoreMissing {
@@ -0,0 +1,70 @@
+package statements
+
+import (
+	"fmt"
+
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/nodes"
+	"github.com/noirbizarre/gonja/parser"
+	"github.com/noirbizarre/gonja/tokens"
+)
+
+type BreakStmt struct {
+	Location *tokens.Token
+}
+
+func (stmt *BreakStmt) Position() *tokens.Token { return stmt.Location }
+func (stmt *BreakStmt) String() string {
+	t := stmt.Position()
+	return fmt.Sprintf("BreakStmt(Line=%d Col=%d)", t.Line, t.Col)
+}
+
+func (stmt *BreakStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
+	return exec.ErrBreak
+}
+
+type ContinueStmt struct {
+	Location *tokens.Token
+}
+
+func (stmt *ContinueStmt) Position() *tokens.Token { return stmt.Location }
+func (stmt *ContinueStmt) String() string {
+	t := stmt.Position()
+	return fmt.Sprintf("ContinueStmt(Line=%d Col=%d)", t.Line, t.Col)
+}
+
+func (stmt *ContinueStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
+	return exec.ErrContinue
+}
+
+// loopControlParser ensures the loop control statement name is used within a loop
+func loopControlParser(name string, p *parser.Parser, args *parser.Parser) error {
+	if p.Loops <= 0 {
+		return args.Error(fmt.Sprintf("'%s' statement is only allowed within a loop.", name), args.Current())
+	}
+	if !args.End() {
+		return args.Error(fmt.Sprintf("'%s' statement does not take any argument.", name), args.Current())
+	}
+	return nil
+}
+
+func breakParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
+	stmt := &BreakStmt{Location: p.Current()}
+	if err := loopControlParser("break", p, args); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func continueParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
+	stmt := &ContinueStmt{Location: p.Current()}
+	if err := loopControlParser("continue", p, args); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+func init() {
+	All.Register("break", breakParser)
+	All.Register("continue", continueParser)
+}
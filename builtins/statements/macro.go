@@ -77,51 +77,59 @@ func (node *MacroStmt) call(ctx *exec.Context, args ...*exec.Value) *exec.Value
 	return nil
 }
 
-func macroParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
-	stmt := &nodes.Macro{
-		Location: p.Current(),
-		Args:     []string{},
-		Kwargs:   []*nodes.Pair{},
-	}
-
-	name := args.Match(tokens.Name)
-	if name == nil {
-		return nil, args.Error("Macro-tag needs at least an identifier as name.", nil)
-	}
-	stmt.Name = name.Val
-
-	if args.Match(tokens.Lparen) == nil {
-		return nil, args.Error("Expected '('.", nil)
-	}
-
+// parseMacroSignature parses the macro arguments up to the closing parenthesis
+func parseMacroSignature(args *parser.Parser, macro *nodes.Macro) error {
 	for args.Match(tokens.Rparen) == nil {
 		argName := args.Match(tokens.Name)
 		if argName == nil {
-			return nil, args.Error("Expected argument name as identifier.", nil)
+			return args.Error("Expected argument name as identifier.", nil)
 		}
 
 		if args.Match(tokens.Assign) != nil {
 			// Default expression follows
 			expr, err := args.ParseExpression()
 			if err != nil {
-				return nil, err
+				return err
 			}
-			stmt.Kwargs = append(stmt.Kwargs, &nodes.Pair{
+			macro.Kwargs = append(macro.Kwargs, &nodes.Pair{
 				Key:   &nodes.String{argName, argName.Val},
 				Value: expr,
 			})
 			// stmt.Kwargs[argName.Val] = expr
 		} else {
-			stmt.Args = append(stmt.Args, argName.Val)
+			macro.Args = append(macro.Args, argName.Val)
 		}
 
 		if args.Match(tokens.Rparen) != nil {
 			break
 		}
 		if args.Match(tokens.Comma) == nil {
-			return nil, args.Error("Expected ',' or ')'.", nil)
+			return args.Error("Expected ',' or ')'.", nil)
 		}
 	}
+	return nil
+}
+
+func macroParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
+	stmt := &nodes.Macro{
+		Location: p.Current(),
+		Args:     []string{},
+		Kwargs:   []*nodes.Pair{},
+	}
+
+	name := args.Match(tokens.Name)
+	if name == nil {
+		return nil, args.Error("Macro-tag needs at least an identifier as name.", nil)
+	}
+	stmt.Name = name.Val
+
+	if args.Match(tokens.Lparen) == nil {
+		return nil, args.Error("Expected '('.", nil)
+	}
+
+	if err := parseMacroSignature(args, stmt); err != nil {
+		return nil, err
+	}
 
 	// if args.MatchName("export") != nil {
 	// 	stmt.exported = true
@@ -131,8 +139,11 @@ func macroParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error)
 		return nil, args.Error("Malformed macro-tag.", nil)
 	}
 
-	// Body wrapping
+	// Body wrapping, loop controls can't reach an enclosing loop from there
+	loops := p.Loops
+	p.Loops = 0
 	wrapper, endargs, err := p.WrapUntil("endmacro")
+	p.Loops = loops
 	if err != nil {
 		return nil, err
 	}
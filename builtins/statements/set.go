@@ -2,6 +2,7 @@ package statements
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/noirbizarre/gonja/exec"
 	"github.com/noirbizarre/gonja/nodes"
@@ -14,6 +15,8 @@ type SetStmt struct {
 	Location   *tokens.Token
 	Target     nodes.Expression
 	Expression nodes.Expression
+	Body       *nodes.Wrapper      // The captured body of a block assignment
+	Filters    []*nodes.FilterCall // The filters applied to the captured body
 }
 
 func (stmt *SetStmt) Position() *tokens.Token { return stmt.Location }
@@ -23,30 +26,82 @@ func (stmt *SetStmt) String() string {
 }
 
 func (stmt *SetStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error {
-	// Evaluate expression
-	value := r.Eval(stmt.Expression)
-	if value.IsError() {
-		return value
+	var value *exec.Value
+	if stmt.Body != nil {
+		var err error
+		value, err = stmt.capture(r)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Evaluate expression
+		value = r.Eval(stmt.Expression)
+		if value.IsError() {
+			return value
+		}
 	}
 
-	switch n := stmt.Target.(type) {
+	return assign(r, stmt.Target, value)
+}
+
+// assign sets value to target, unpacking it if target is a tuple
+func assign(r *exec.Renderer, target nodes.Expression, value *exec.Value) error {
+	var stored interface{} = value.Interface()
+	if value.Safe || value.IsUndefined() {
+		// Keep the value as is to prevent it from being escaped again
+		// or to remain undefined
+		stored = value
+	}
+
+	switch n := target.(type) {
 	case *nodes.Name:
-		r.Ctx.Set(n.Name.Val, value.Interface())
+		r.Ctx.Set(n.Name.Val, stored)
 	case *nodes.Getattr:
-		target := r.Eval(n.Node)
-		if target.IsError() {
-			return errors.Wrapf(target, `Unable to evaluate target %s`, n)
+		owner := r.Eval(n.Node)
+		if owner.IsError() {
+			return errors.Wrapf(owner, `Unable to evaluate target %s`, n)
+		}
+		if err := r.Evaluator().CheckSetattr(owner, n.Attr, n.Position()); err != nil {
+			return err
 		}
-		if err := target.Set(n.Attr, value.Interface()); err != nil {
+		if err := owner.Set(n.Attr, stored); err != nil {
 			return errors.Wrapf(err, `Unable to set value on "%s"`, n.Attr)
 		}
 	case *nodes.Getitem:
-		target := r.Eval(n.Node)
-		if target.IsError() {
-			return errors.Wrapf(target, `Unable to evaluate target %s`, n)
+		owner := r.Eval(n.Node)
+		if owner.IsError() {
+			return errors.Wrapf(owner, `Unable to evaluate target %s`, n)
 		}
-		if err := target.Set(n.Arg, value.Interface()); err != nil {
-			return errors.Wrapf(err, `Unable to set value on "%s"`, n.Arg)
+		name := n.Arg
+		if n.Key != nil {
+			key := r.Eval(n.Key)
+			if key.IsError() {
+				return errors.Wrapf(key, `Unable to evaluate item %s`, n.Key)
+			}
+			name = key.String()
+		}
+		if err := r.Evaluator().CheckSetattr(owner, name, n.Position()); err != nil {
+			return err
+		}
+		if err := owner.Set(name, stored); err != nil {
+			return errors.Wrapf(err, `Unable to set value on "%s"`, name)
+		}
+	case *nodes.Tuple:
+		if !value.IsIterable() {
+			return errors.Errorf(`Unable to unpack %s: %T is not iterable`, value.String(), value.Interface())
+		}
+		items := []*exec.Value{}
+		value.Iterate(func(idx, count int, key, value *exec.Value) bool {
+			items = append(items, key)
+			return true
+		}, func() {})
+		if len(items) != len(n.Val) {
+			return errors.Errorf(`Unable to unpack %d values into %d targets`, len(items), len(n.Val))
+		}
+		for idx, item := range items {
+			if err := assign(r, n.Val[idx], item); err != nil {
+				return err
+			}
 		}
 	default:
 		return errors.Errorf(`Illegal set target node %s`, n)
@@ -55,25 +110,60 @@ func (stmt *SetStmt) Execute(r *exec.Renderer, tag *nodes.StatementBlock) error
 	return nil
 }
 
+// capture renders the body of a block assignment and applies its filters.
+// The captured markup is safe when rendered with autoescape.
+func (stmt *SetStmt) capture(r *exec.Renderer) (*exec.Value, error) {
+	var out strings.Builder
+	sub := r.Inherit()
+	sub.Out = &out
+	if err := sub.ExecuteWrapper(stmt.Body); err != nil {
+		return nil, err
+	}
+
+	value := exec.AsValue(out.String())
+	if r.Autoescape {
+		value = exec.AsSafeValue(out.String())
+	}
+	for _, call := range stmt.Filters {
+		value = r.Evaluator().ExecuteFilter(call, value)
+		if value.IsError() {
+			return nil, errors.Wrapf(value, `Unable to apply filter %s`, call.Name)
+		}
+	}
+	return value, nil
+}
+
 func setParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
 	stmt := &SetStmt{
 		Location: p.Current(),
 	}
 
-	// Parse variable name
-	ident, err := args.ParseVariable()
+	// Parse variable names
+	target, err := parseSetTarget(args)
 	if err != nil {
-		return nil, errors.Wrap(err, `Unable to parse identifier`)
+		return nil, err
 	}
-	switch n := ident.(type) {
-	case *nodes.Name, *nodes.Call, *nodes.Getitem, *nodes.Getattr:
-		stmt.Target = n
-	default:
-		return nil, errors.Errorf(`Unexpected set target %s`, n)
+	stmt.Target = target
+	if comma := args.Match(tokens.Comma); comma != nil {
+		tuple := &nodes.Tuple{Location: comma, Val: []nodes.Expression{target}}
+		for {
+			target, err := parseSetTarget(args)
+			if err != nil {
+				return nil, err
+			}
+			tuple.Val = append(tuple.Val, target)
+			if args.Match(tokens.Comma) == nil {
+				break
+			}
+		}
+		stmt.Target = tuple
 	}
 
 	if args.Match(tokens.Assign) == nil {
-		return nil, args.Error("Expected '='.", args.Current())
+		if _, ok := stmt.Target.(*nodes.Tuple); ok {
+			return nil, args.Error("Expected '='.", args.Current())
+		}
+		return parseSetBlock(p, args, stmt)
 	}
 
 	// Variable expression
@@ -81,6 +171,21 @@ func setParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
 	if err != nil {
 		return nil, err
 	}
+	if comma := args.Match(tokens.Comma); comma != nil {
+		// Implicit tuple: {% set a, b = b, a %}
+		tuple := &nodes.Tuple{Location: comma, Val: []nodes.Expression{expr}}
+		for !args.End() {
+			expr, err := args.ParseExpression()
+			if err != nil {
+				return nil, err
+			}
+			tuple.Val = append(tuple.Val, expr)
+			if args.Match(tokens.Comma) == nil {
+				break
+			}
+		}
+		expr = tuple
+	}
 	stmt.Expression = expr
 
 	// Remaining arguments
@@ -91,6 +196,48 @@ func setParser(p *parser.Parser, args *parser.Parser) (nodes.Statement, error) {
 	return stmt, nil
 }
 
+// parseSetTarget parses a single assignment target: a name, an attribute or an item
+func parseSetTarget(args *parser.Parser) (nodes.Expression, error) {
+	ident, err := args.ParseVariable()
+	if err != nil {
+		return nil, errors.Wrap(err, `Unable to parse identifier`)
+	}
+	switch n := ident.(type) {
+	case *nodes.Name, *nodes.Getitem, *nodes.Getattr:
+		return n, nil
+	default:
+		return nil, args.Error(fmt.Sprintf(`Unexpected set target %s`, n), n.Position())
+	}
+}
+
+// parseSetBlock parses a block assignment:
+// {% set name | filters %}body{% endset %}
+func parseSetBlock(p *parser.Parser, args *parser.Parser, stmt *SetStmt) (nodes.Statement, error) {
+	for args.Match(tokens.Pipe) != nil {
+		filter, err := args.ParseFilter()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Filters = append(stmt.Filters, filter)
+	}
+
+	if !args.End() {
+		return nil, args.Error("Expected '='.", args.Current())
+	}
+
+	wrapper, endargs, err := p.WrapUntil("endset")
+	if err != nil {
+		return nil, err
+	}
+	stmt.Body = wrapper
+
+	if !endargs.End() {
+		return nil, endargs.Error("Arguments not allowed here.", nil)
+	}
+
+	return stmt, nil
+}
+
 func init() {
 	All.Register("set", setParser)
 }
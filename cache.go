@@ -0,0 +1,136 @@
+package gonja
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/noirbizarre/gonja/exec"
+)
+
+// TemplateCache stores the compiled templates by name.
+// Implementations must be safe for concurrent use.
+type TemplateCache interface {
+	// Get returns the cached template, if any
+	Get(name string) (*exec.Template, bool)
+	// Peek returns the cached template, if any, without counting
+	// a hit or a miss nor marking the template as recently used
+	Peek(name string) (*exec.Template, bool)
+	// Set adds or replaces a template
+	Set(name string, tpl *exec.Template)
+	// Delete removes a template, if cached
+	Delete(name string)
+	// Clear removes all the templates
+	Clear()
+	// Stats returns the cache counters
+	Stats() CacheStats
+}
+
+// CacheStats holds the counters of a TemplateCache
+type CacheStats struct {
+	Hits      uint64 // The number of Get calls finding a template
+	Misses    uint64 // The number of Get calls not finding a template
+	Evictions uint64 // The number of templates removed to make room for new ones
+	Size      int    // The number of cached templates
+}
+
+type lruEntry struct {
+	name string
+	tpl  *exec.Template
+}
+
+// LRUCache is a TemplateCache holding a bounded number of templates.
+// The least recently used template is evicted when it is full.
+type LRUCache struct {
+	mu      sync.Mutex
+	maxSize int
+	order   *list.List // The entries, most recently used first
+	entries map[string]*list.Element
+	stats   CacheStats
+}
+
+// NewLRUCache creates an LRUCache holding at most maxSize templates.
+// A maxSize of 0 or less means no limit.
+func NewLRUCache(maxSize int) *LRUCache {
+	return &LRUCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Get implements TemplateCache
+func (c *LRUCache) Get(name string) (*exec.Template, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[name]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).tpl, true
+}
+
+// Peek implements TemplateCache
+func (c *LRUCache) Peek(name string) (*exec.Template, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*lruEntry).tpl, true
+}
+
+// Set implements TemplateCache
+func (c *LRUCache) Set(name string, tpl *exec.Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[name]; ok {
+		elem.Value.(*lruEntry).tpl = tpl
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[name] = c.order.PushFront(&lruEntry{name: name, tpl: tpl})
+	for c.maxSize > 0 && c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).name)
+		c.stats.Evictions++
+	}
+}
+
+// Delete implements TemplateCache
+func (c *LRUCache) Delete(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[name]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, name)
+	}
+}
+
+// Clear implements TemplateCache
+func (c *LRUCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = map[string]*list.Element{}
+}
+
+// Stats implements TemplateCache
+func (c *LRUCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// compilation is an ongoing template compilation shared by concurrent callers
+type compilation struct {
+	done chan struct{}
+	tpl  *exec.Template
+	err  error
+}
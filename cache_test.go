@@ -0,0 +1,198 @@
+package gonja_test
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+func TestLRUCache(t *testing.T) {
+	assert := assert.New(t)
+	cache := gonja.NewLRUCache(2)
+	a, b, c := &exec.Template{Name: "a"}, &exec.Template{Name: "b"}, &exec.Template{Name: "c"}
+
+	cache.Set("a", a)
+	cache.Set("b", b)
+	tpl, ok := cache.Get("a")
+	assert.True(ok)
+	assert.True(a == tpl)
+
+	// "b" is the least recently used
+	cache.Set("c", c)
+	_, ok = cache.Get("b")
+	assert.False(ok)
+	_, ok = cache.Get("c")
+	assert.True(ok)
+	assert.Equal(gonja.CacheStats{Hits: 2, Misses: 1, Evictions: 1, Size: 2}, cache.Stats())
+
+	// Replacing doesn't evict
+	cache.Set("a", c)
+	tpl, _ = cache.Get("a")
+	assert.True(c == tpl)
+	assert.Equal(uint64(1), cache.Stats().Evictions)
+
+	cache.Delete("a")
+	_, ok = cache.Get("a")
+	assert.False(ok)
+	assert.Equal(1, cache.Stats().Size)
+
+	// Peeking is neither counted nor marks the template as used
+	cache.Set("b", b)
+	tpl, ok = cache.Peek("b")
+	assert.True(ok)
+	assert.True(b == tpl)
+	_, ok = cache.Peek("a")
+	assert.False(ok)
+	assert.Equal(gonja.CacheStats{Hits: 3, Misses: 2, Evictions: 1, Size: 2}, cache.Stats())
+	cache.Set("a", a)
+	_, ok = cache.Peek("c")
+	assert.False(ok, "c should have been evicted despite being peeked")
+
+	cache.Clear()
+	assert.Equal(gonja.CacheStats{Hits: 3, Misses: 2, Evictions: 2, Size: 0}, cache.Stats())
+}
+
+func TestLRUCacheUnbounded(t *testing.T) {
+	cache := gonja.NewLRUCache(0)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		cache.Set(name, &exec.Template{Name: name})
+	}
+	assert.Equal(t, gonja.CacheStats{Size: 5}, cache.Stats())
+}
+
+func TestEnvironmentCache(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(loaders.NewMapLoader(map[string]string{
+		"a.html": "a",
+		"b.html": "b",
+	}))
+	env.Cache = gonja.NewLRUCache(1)
+
+	first, err := env.FromCache("a.html")
+	assert.Nil(err)
+	again, _ := env.FromCache("a.html")
+	assert.True(first == again)
+
+	_, err = env.FromCache("b.html")
+	assert.Nil(err)
+	again, _ = env.FromCache("a.html")
+	assert.False(first == again, "a.html should have been evicted")
+	assert.Equal(gonja.CacheStats{Hits: 1, Misses: 3, Evictions: 2, Size: 1}, env.Cache.Stats())
+
+	env.CleanCache("a.html")
+	assert.Equal(0, env.Cache.Stats().Size)
+
+	_, err = env.FromCache("missing.html")
+	assert.NotNil(err)
+	assert.Equal(0, env.Cache.Stats().Size)
+}
+
+// blockingLoader blocks the loading of "slow.html" until released
+type blockingLoader struct {
+	*loaders.MapLoader
+	release chan struct{}
+	loads   int32
+}
+
+func (l *blockingLoader) Get(name string) (io.Reader, error) {
+	if name == "slow.html" {
+		atomic.AddInt32(&l.loads, 1)
+		<-l.release
+	}
+	return l.MapLoader.Get(name)
+}
+
+func TestEnvironmentCacheSingleFlight(t *testing.T) {
+	assert := assert.New(t)
+	loader := &blockingLoader{
+		MapLoader: loaders.NewMapLoader(map[string]string{"slow.html": "slow", "fast.html": "fast"}),
+		release:   make(chan struct{}),
+	}
+	env := tu.NewEnv(loader)
+
+	var wg sync.WaitGroup
+	templates := make([]*exec.Template, 10)
+	for idx := range templates {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			tpl, err := env.FromCache("slow.html")
+			assert.Nil(err)
+			templates[idx] = tpl
+		}(idx)
+	}
+
+	// Other templates are not blocked by the slow compilation
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := env.FromCache("fast.html")
+		assert.Nil(err)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fast.html compilation has been blocked by slow.html")
+	}
+
+	close(loader.release)
+	wg.Wait()
+	assert.Equal(int32(1), atomic.LoadInt32(&loader.loads))
+	for _, tpl := range templates {
+		assert.True(templates[0] == tpl)
+	}
+}
+
+// panickingLoader panics while loading "panic.html" until disarmed
+type panickingLoader struct {
+	*loaders.MapLoader
+	armed int32
+}
+
+func (l *panickingLoader) Get(name string) (io.Reader, error) {
+	if name == "panic.html" && atomic.LoadInt32(&l.armed) == 1 {
+		panic("broken loader")
+	}
+	return l.MapLoader.Get(name)
+}
+
+func TestEnvironmentCachePanic(t *testing.T) {
+	assert := assert.New(t)
+	loader := &panickingLoader{
+		MapLoader: loaders.NewMapLoader(map[string]string{"panic.html": "recovered"}),
+		armed:     1,
+	}
+	env := tu.NewEnv(loader)
+
+	_, err := env.FromCache("panic.html")
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "broken loader")
+	}
+
+	// The failed compilation doesn't block the next ones
+	atomic.StoreInt32(&loader.armed, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tpl, err := env.FromCache("panic.html")
+		if assert.Nil(err) {
+			out, err := tpl.Execute(nil)
+			assert.Nil(err)
+			assert.Equal("recovered", out)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("panic.html compilation is still pending")
+	}
+}
@@ -0,0 +1,129 @@
+package gonja_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+type ctxKey string
+
+func TestExecuteContextCancelled(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("Hello {{ name }}")
+	if !assert.Nil(err) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var w bytes.Buffer
+	err = tpl.ExecuteContext(ctx, map[string]interface{}{"name": "World"}, &w)
+	if assert.NotNil(err) {
+		assert.Equal(context.Canceled, errors.Cause(err))
+	}
+	assert.Equal("", w.String())
+}
+
+func TestExecuteContextInterruptsLoops(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("{% for i in range(10000) %}{{ tick(i) }}{% endfor %}")
+	if !assert.Nil(err) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := 0
+	data := map[string]interface{}{
+		"tick": func(i int) int {
+			ticks++
+			if ticks == 10 {
+				cancel()
+			}
+			return i
+		},
+	}
+	var w bytes.Buffer
+	err = tpl.ExecuteContext(ctx, data, &w)
+	if assert.NotNil(err) {
+		assert.Equal(context.Canceled, errors.Cause(err))
+	}
+	assert.Equal(10, ticks)
+}
+
+func TestExecuteContextDeadline(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("{% for i in range(1000) %}{{ slow() }}{% endfor %}")
+	if !assert.Nil(err) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	data := map[string]interface{}{
+		"slow": func() string {
+			time.Sleep(5 * time.Millisecond)
+			return "."
+		},
+	}
+	var w bytes.Buffer
+	err = tpl.ExecuteContext(ctx, data, &w)
+	if assert.NotNil(err) {
+		assert.Equal(context.DeadlineExceeded, errors.Cause(err))
+	}
+}
+
+func TestExecuteContextInterruptsMacros(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("{% macro m(arg) %}macro{% endmacro %}{{ m(stop()) }}")
+	if !assert.Nil(err) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	data := map[string]interface{}{
+		"stop": func() string {
+			cancel()
+			return ""
+		},
+	}
+	var w bytes.Buffer
+	err = tpl.ExecuteContext(ctx, data, &w)
+	if assert.NotNil(err) {
+		assert.Equal(context.Canceled, errors.Cause(err))
+		assert.Contains(err.Error(), "Unable to execute macro 'm'")
+	}
+}
+
+func TestExecuteContextValues(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Filters.Register("whoami", func(e *exec.Evaluator, in *exec.Value, params *exec.VarArgs) *exec.Value {
+		return exec.AsValue(in.String() + e.Context().Value(ctxKey("user")).(string))
+	})
+	env.Globals.Set("user", func(ctx context.Context) string {
+		return ctx.Value(ctxKey("user")).(string)
+	})
+	env.Globals.Set("greet", func(ctx context.Context, greeting string) string {
+		return greeting + " " + ctx.Value(ctxKey("user")).(string)
+	})
+	env.Globals.Set("greetVarArgs", func(ctx context.Context, va *exec.VarArgs) string {
+		return va.First().String() + " " + ctx.Value(ctxKey("user")).(string)
+	})
+	tpl, err := env.FromString("{{ user() }}|{{ 'I am '|whoami }}|{{ greet('Hi') }}|{{ greetVarArgs('Hey') }}")
+	if !assert.Nil(err) {
+		return
+	}
+	ctx := context.WithValue(context.Background(), ctxKey("user"), "john")
+	var w bytes.Buffer
+	err = tpl.ExecuteContext(ctx, nil, &w)
+	if assert.Nil(err) {
+		assert.Equal("john|I am john|Hi john|Hey john", w.String())
+	}
+}
@@ -7,6 +7,11 @@ type Inheritable interface {
 // Config holds plexer and parser parameters
 type Config struct {
 	Debug bool
+	// If this is set to True the cached templates are recompiled
+	// when their source or the source of a template they depend on changes.
+	// It requires a loader reporting the template versions.
+	// Defaults to False.
+	AutoReload bool
 	// The string marking the beginning of a block. Defaults to '{%'
 	BlockStartString string
 	// The string marking the end of a block. Defaults to '%}'.
@@ -52,6 +57,7 @@ type Config struct {
 func NewConfig() *Config {
 	return &Config{
 		Debug:               false,
+		AutoReload:          false,
 		BlockStartString:    "{%",
 		BlockEndString:      "%}",
 		VariableStartString: "{{",
@@ -74,12 +80,15 @@ func (cfg *Config) Inherit() *Config {
 	}
 	return &Config{
 		Debug:               cfg.Debug,
+		AutoReload:          cfg.AutoReload,
 		BlockStartString:    cfg.BlockStartString,
 		BlockEndString:      cfg.BlockEndString,
 		VariableStartString: cfg.VariableStartString,
 		VariableEndString:   cfg.VariableEndString,
 		CommentStartString:  cfg.CommentStartString,
 		CommentEndString:    cfg.CommentEndString,
+		LineStatementPrefix: cfg.LineStatementPrefix,
+		LineCommentPrefix:   cfg.LineCommentPrefix,
 		TrimBlocks:          cfg.TrimBlocks,
 		LstripBlocks:        cfg.LstripBlocks,
 		NewlineSequence:     cfg.NewlineSequence,
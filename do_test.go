@@ -0,0 +1,47 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+type counter struct {
+	Count int
+}
+
+func (c *counter) Incr(step int) int {
+	c.Count += step
+	return c.Count
+}
+
+func TestDo(t *testing.T) {
+	assert := assert.New(t)
+	ctr := &counter{}
+	out, err := tu.Render(t, tu.NewEnv(nil), "{% for i in range(3) %}{% do counter.Incr(i) %}{% endfor %}{{ counter.Count }}", map[string]interface{}{"counter": ctr})
+	assert.Nil(err)
+	assert.Equal("3", out)
+	assert.Equal(3, ctr.Count)
+}
+
+func TestDoErrors(t *testing.T) {
+	assert := assert.New(t)
+	_, err := gonja.FromString("{% do %}")
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "An expression is required for 'do' statement.")
+	}
+
+	failure := errors.New("failure")
+	_, err = tu.Render(t, tu.NewEnv(nil), "line\n  {% do fail() %}", map[string]interface{}{"fail": func() (string, error) { return "", failure }})
+	te, ok := exec.AsTemplateError(err)
+	if assert.True(ok, "expected a TemplateError, got %v", err) {
+		assert.Equal(failure, errors.Cause(err))
+		assert.Equal(2, te.Line)
+		assert.Equal(3, te.Column)
+	}
+}
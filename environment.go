@@ -2,9 +2,11 @@ package gonja
 
 import (
 	"io/ioutil"
+	"strings"
 	"sync"
 
 	"github.com/goph/emperror"
+	"github.com/pkg/errors"
 
 	"github.com/noirbizarre/gonja/builtins"
 	"github.com/noirbizarre/gonja/config"
@@ -16,20 +18,26 @@ type Environment struct {
 	*exec.EvalConfig
 	Loader loaders.Loader
 
-	Cache      map[string]*exec.Template
-	CacheMutex sync.Mutex
+	// Cache holds the templates compiled by FromCache,
+	// an unbounded LRUCache by default.
+	Cache TemplateCache
+
+	mu        sync.Mutex
+	compiling map[string]*compilation // The ongoing FromCache compilations
 }
 
 func NewEnvironment(cfg *config.Config, loader loaders.Loader) *Environment {
 	env := &Environment{
 		EvalConfig: exec.NewEvalConfig(cfg),
 		Loader:     loader,
-		Cache:      map[string]*exec.Template{},
+		Cache:      NewLRUCache(0),
+		compiling:  map[string]*compilation{},
 	}
 	env.EvalConfig.Loader = env
 	env.Filters.Update(builtins.Filters)
 	env.Statements.Update(builtins.Statements)
 	env.Tests.Update(builtins.Tests)
+	env.Escapers.Update(builtins.Escapers)
 	env.Globals.Merge(builtins.Globals)
 	env.Globals.Set("gonja", map[string]interface{}{
 		"version": VERSION,
@@ -37,52 +45,165 @@ func NewEnvironment(cfg *config.Config, loader loaders.Loader) *Environment {
 	return env
 }
 
+// NewSandboxedEnvironment creates an Environment rendering templates
+// under the given security policy. A default SandboxPolicy is used if policy is nil.
+// Denied accesses are reported as exec.SecurityError.
+func NewSandboxedEnvironment(cfg *config.Config, loader loaders.Loader, policy exec.SecurityPolicy) *Environment {
+	env := NewEnvironment(cfg, loader)
+	if policy == nil {
+		policy = exec.NewSandboxPolicy()
+	}
+	env.Policy = policy
+	return env
+}
+
 // CleanCache cleans the template cache. If filenames is not empty,
 // it will remove the template caches of those filenames.
 // Or it will empty the whole template cache. It is thread-safe.
 func (env *Environment) CleanCache(filenames ...string) {
-	env.CacheMutex.Lock()
-	defer env.CacheMutex.Unlock()
-
 	if len(filenames) == 0 {
-		env.Cache = map[string]*exec.Template{}
+		env.Cache.Clear()
 	}
 
 	for _, filename := range filenames {
-		delete(env.Cache, filename)
+		env.Cache.Delete(filename)
 	}
 }
 
 // FromCache is a convenient method to cache templates. It is thread-safe
-// and will only compile the template associated with a filename once.
+// and will only compile the template associated with a filename once:
+// concurrent calls for the same filename wait for a single compilation
+// without blocking the calls for other templates.
 // If Environment.Debug is true (for example during development phase),
 // FromCache() will not cache the template and instead recompile it on any
 // call (to make changes to a template live instantaneously).
+// If Config.AutoReload is true, the cached template is recompiled only
+// when its source or the source of one of its dependencies has changed
+// according to the loader (see loaders.Versioner).
 func (env *Environment) FromCache(filename string) (*exec.Template, error) {
 	if env.Config.Debug {
 		// Recompile on any request
 		return env.FromFile(filename)
 	}
 
-	env.CacheMutex.Lock()
-	defer env.CacheMutex.Unlock()
+	// Outdated templates are removed before the lookup so they count as misses
+	if env.Config.AutoReload {
+		if tpl, has := env.Cache.Peek(filename); has && !env.isUpToDate(tpl) {
+			env.Cache.Delete(filename)
+		}
+	}
 
-	tpl, has := env.Cache[filename]
+	// Cache hit
+	if tpl, has := env.Cache.Get(filename); has {
+		return tpl, nil
+	}
 
-	// Cache miss
-	if !has {
-		tpl, err := env.FromFile(filename)
-		if err != nil {
-			return nil, err
-		}
-		env.Cache[filename] = tpl
+	// Cache miss, wait for the ongoing compilation if any
+	env.mu.Lock()
+	if current, ok := env.compiling[filename]; ok {
+		env.mu.Unlock()
+		<-current.done
+		return current.tpl, current.err
+	}
+	// The template may have been compiled since the lookup
+	if tpl, has := env.Cache.Peek(filename); has {
+		env.mu.Unlock()
 		return tpl, nil
 	}
+	current := &compilation{done: make(chan struct{})}
+	env.compiling[filename] = current
+	env.mu.Unlock()
 
-	// Cache hit
+	env.compileShared(filename, current)
+	return current.tpl, current.err
+}
+
+// compileShared runs the compilation shared by concurrent FromCache callers.
+// A panic is reported as an error so the waiting callers are always released.
+func (env *Environment) compileShared(filename string, current *compilation) {
+	defer func() {
+		if r := recover(); r != nil {
+			current.tpl, current.err = nil, emperror.With(errors.Errorf("Unable to compile template: %v", r), "filename", filename)
+		}
+		env.mu.Lock()
+		delete(env.compiling, filename)
+		env.mu.Unlock()
+		close(current.done)
+	}()
+	current.tpl, current.err = env.compile(filename)
+}
+
+// compile compiles a template and caches it
+func (env *Environment) compile(filename string) (*exec.Template, error) {
+	// Get the version first so a change while compiling triggers a new compilation
+	version := env.TemplateVersion(filename)
+	tpl, err := env.FromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if env.Config.AutoReload {
+		// The dependencies versions are recorded while loading them
+		if tpl.Versions == nil {
+			tpl.Versions = map[string]string{}
+		}
+		tpl.Versions[filename] = version
+	}
+	env.Cache.Set(filename, tpl)
 	return tpl, nil
 }
 
+// TemplateVersion returns the version of the named template reported by the Loader
+// or an empty string if it is unknown (ie. the template is missing).
+func (env *Environment) TemplateVersion(name string) string {
+	version, err := loaders.Version(env.Loader, name)
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+// isUpToDate returns true if the cached template and its dependencies didn't change
+func (env *Environment) isUpToDate(tpl *exec.Template) bool {
+	for name, version := range tpl.Versions {
+		if env.TemplateVersion(name) != version {
+			return false
+		}
+	}
+	return true
+}
+
+// CompileErrors holds the failures of Environment.CompileAll
+type CompileErrors []error
+
+func (e CompileErrors) Error() string {
+	msgs := make([]string, len(e))
+	for idx, err := range e {
+		msgs[idx] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// CompileAll compiles and caches all the templates listed by the Loader
+// (only those having one of the given extensions if any, ie. ".html").
+// It doesn't stop on the first failure, all of them are returned as CompileErrors.
+// An error is returned if the Loader is not a loaders.Lister.
+func (env *Environment) CompileAll(extensions ...string) error {
+	names, err := loaders.ListTemplates(env.Loader, extensions...)
+	if err != nil {
+		return err
+	}
+	var errs CompileErrors
+	for _, name := range names {
+		if _, err := env.FromCache(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 // FromString loads a template from string and returns a Template instance.
 func (env *Environment) FromString(tpl string) (*exec.Template, error) {
 	return exec.NewTemplate("string", tpl, env.EvalConfig)
@@ -107,6 +228,37 @@ func (env *Environment) FromFile(filename string) (*exec.Template, error) {
 	return exec.NewTemplate(filename, string(buf), env.EvalConfig)
 }
 
+// LintString returns all the syntax errors of the given template source
+// or nil if it is valid.
+func (env *Environment) LintString(source string) []*exec.TemplateError {
+	return exec.Lint("string", source, env.EvalConfig)
+}
+
+// LintFile returns all the syntax errors of the given template file
+// or nil if it is valid.
+// An error is returned if the file can't be read.
+func (env *Environment) LintFile(filename string) ([]*exec.TemplateError, error) {
+	fd, err := env.Loader.Get(filename)
+	if err != nil {
+		return nil, emperror.With(err, "filename", filename)
+	}
+	buf, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return nil, emperror.With(err, "filename", filename)
+	}
+
+	return exec.Lint(filename, string(buf), env.EvalConfig), nil
+}
+
+// ResolveTemplate resolves a template name loaded from the parent template
+// if the Loader is a loaders.Resolver.
+func (env *Environment) ResolveTemplate(parent, name string) string {
+	if resolver, ok := env.Loader.(loaders.Resolver); ok {
+		return resolver.Resolve(parent, name)
+	}
+	return name
+}
+
 func (env *Environment) GetTemplate(filename string) (*exec.Template, error) {
 	return env.FromFile(filename)
 }
@@ -14,6 +14,14 @@ type EvalConfig struct {
 	Statements *StatementSet
 	Tests      *TestSet
 	Loader     TemplateLoader
+	Policy     SecurityPolicy // Sandbox security policy, nil means unrestricted
+	Limits     Limits
+	Undefined  UndefinedPolicy // How undefined values behave, nil means DefaultUndefined
+	Escapers   *EscaperSet
+	// The escaper used when autoescaping, HTML escaping is used if empty
+	Escaper string
+	// Chooses the autoescaping mode of each template from its name if set
+	AutoescapeSelector AutoescapeSelector
 }
 
 func NewEvalConfig(cfg *config.Config) *EvalConfig {
@@ -23,6 +31,7 @@ func NewEvalConfig(cfg *config.Config) *EvalConfig {
 		Filters:    &FilterSet{},
 		Statements: &StatementSet{},
 		Tests:      &TestSet{},
+		Escapers:   &EscaperSet{},
 	}
 }
 
@@ -34,7 +43,23 @@ func (cfg *EvalConfig) Inherit() *EvalConfig {
 		Statements: cfg.Statements,
 		Tests:      cfg.Tests,
 		Loader:     cfg.Loader,
+		Policy:     cfg.Policy,
+		Limits:     cfg.Limits,
+		Undefined:  cfg.Undefined,
+		Escapers:   cfg.Escapers,
+		Escaper:    cfg.Escaper,
+
+		AutoescapeSelector: cfg.AutoescapeSelector,
+	}
+}
+
+// ResolveTemplate resolves a template name loaded from the parent template.
+// The name is returned unchanged if the Loader is not a TemplateResolver.
+func (cfg *EvalConfig) ResolveTemplate(parent, name string) string {
+	if resolver, ok := cfg.Loader.(TemplateResolver); ok {
+		return resolver.ResolveTemplate(parent, name)
 	}
+	return name
 }
 
 func (cfg *EvalConfig) GetTemplate(filename string) (*nodes.Template, error) {
@@ -0,0 +1,287 @@
+package exec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/noirbizarre/gonja/parser"
+	"github.com/noirbizarre/gonja/tokens"
+)
+
+// Phase is the template processing step an error occurred in
+type Phase string
+
+const (
+	LexPhase    Phase = "lex"
+	ParsePhase  Phase = "parse"
+	RenderPhase Phase = "render"
+)
+
+// Frame is an entry of the template call stack
+type Frame struct {
+	Kind     string        // "include", "extends", "block", "import", "macro", "loop" or "load"
+	Name     string        // The called template, block or macro name
+	Template string        // The calling template
+	Token    *tokens.Token // The call position in the calling template, if known
+
+	body string // The template holding the called body
+}
+
+func (f Frame) String() string {
+	s := fmt.Sprintf(`%s "%s"`, f.Kind, f.Name)
+	if f.Token != nil {
+		s += fmt.Sprintf(" (Line: %d Col: %d)", f.Token.Line, f.Token.Col)
+	}
+	return s
+}
+
+// TemplateError is an error located in a template.
+// Use the "%+v" verb to print it along with a source excerpt and the call stack.
+type TemplateError struct {
+	Name    string // The template name
+	Line    int
+	Column  int
+	Token   *tokens.Token // The offending token, if known
+	Phase   Phase
+	Message string
+	Stack   []Frame // The call stack, outermost first
+	Source  string  // The template source, if available
+
+	cause error
+}
+
+func (e *TemplateError) Error() string {
+	near := ""
+	if e.Token != nil && e.Token.Type != tokens.Error {
+		near = fmt.Sprintf(`, near "%s"`, e.Token.Val)
+	}
+	return fmt.Sprintf(`%s error in template '%s' (Line: %d Col: %d%s): %s`,
+		e.Phase, e.Name, e.Line, e.Column, near, e.Message)
+}
+
+// Cause returns the underlying error
+func (e *TemplateError) Cause() error {
+	return e.cause
+}
+
+// Format implements fmt.Formatter.
+// The "%+v" verb adds a caret-annotated source excerpt and the call stack.
+func (e *TemplateError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		io.WriteString(s, e.Error())
+		if excerpt := e.Excerpt(2); excerpt != "" {
+			io.WriteString(s, "\n")
+			io.WriteString(s, excerpt)
+		}
+		for idx := len(e.Stack) - 1; idx >= 0; idx-- {
+			frame := e.Stack[idx]
+			fmt.Fprintf(s, "\n  from %s in template '%s'", frame, frame.Template)
+		}
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+// Excerpt returns the source lines around the error (with context lines before and after)
+// with a caret pointing at the error position.
+// It returns an empty string if the source is not available.
+func (e *TemplateError) Excerpt(context int) string {
+	if e.Source == "" || e.Line <= 0 {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSuffix(e.Source, "\n"), "\n")
+	if e.Line > len(lines) {
+		return ""
+	}
+	first := e.Line - context
+	if first < 1 {
+		first = 1
+	}
+	last := e.Line + context
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(fmt.Sprint(last))
+
+	var out strings.Builder
+	for num := first; num <= last; num++ {
+		line := strings.TrimRight(lines[num-1], "\r")
+		marker := " "
+		if num == e.Line {
+			marker = ">"
+		}
+		fmt.Fprintf(&out, "%s %*d | %s\n", marker, width, num, line)
+		if num == e.Line {
+			fmt.Fprintf(&out, "  %s | %s\n", strings.Repeat(" ", width), e.caret(line))
+		}
+	}
+	return strings.TrimRight(out.String(), "\n")
+}
+
+// caret builds the caret line pointing at the error column of line
+func (e *TemplateError) caret(line string) string {
+	col := e.Column - 1
+	if col < 0 {
+		col = 0
+	}
+	if col > len(line) {
+		col = len(line)
+	}
+	// Keep tabs to stay aligned with the source line
+	indent := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, line[:col])
+	length := 1
+	if e.Token != nil && e.Token.Type != tokens.Error {
+		val := strings.SplitN(e.Token.Val, "\n", 2)[0]
+		if len(val) > length {
+			length = len(val)
+		}
+		if col+length > len(line) && len(line) > col {
+			length = len(line) - col
+		}
+	}
+	return indent + strings.Repeat("^", length)
+}
+
+// AsTemplateError returns the first TemplateError found in the err causes chain
+func AsTemplateError(err error) (*TemplateError, bool) {
+	for err != nil {
+		if te, ok := err.(*TemplateError); ok {
+			return te, true
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			return nil, false
+		}
+		err = causer.Cause()
+	}
+	return nil, false
+}
+
+// positioned is implemented by errors knowing their position
+type positioned interface {
+	Position() *tokens.Token
+}
+
+// innermostToken returns the deepest known position in the err causes chain
+func innermostToken(err error) *tokens.Token {
+	var token *tokens.Token
+	for err != nil {
+		if p, ok := err.(positioned); ok && p.Position() != nil {
+			token = p.Position()
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = causer.Cause()
+	}
+	return token
+}
+
+// parseError converts a lexing or parsing error into a TemplateError
+func parseError(name string, source string, stream *tokens.Stream, err error) *TemplateError {
+	if te, ok := AsTemplateError(err); ok {
+		// Error from an included or extended template
+		frame := Frame{Kind: "load", Name: te.Name, Template: name, body: te.Name}
+		te.Stack = append([]Frame{frame}, te.Stack...)
+		return te
+	}
+	te := &TemplateError{
+		Name:    name,
+		Phase:   ParsePhase,
+		Message: err.Error(),
+		Source:  source,
+		cause:   err,
+	}
+	var token *tokens.Token
+	if perr, ok := findParserError(err); ok {
+		te.Message = perr.Message
+		token = perr.Token
+	}
+	if token == nil {
+		// Fallback on the enclosing statement start
+		token = statementToken(err)
+	}
+	if stream != nil && stream.IsError() {
+		token = stream.Current()
+	}
+	if token != nil {
+		if token.Type == tokens.Error {
+			te.Phase = LexPhase
+			te.Message = token.Val
+		}
+		te.Token = token
+		te.Line = token.Line
+		te.Column = token.Col
+	}
+	return te
+}
+
+// findParserError returns the first parser.Error found in the err causes chain
+func findParserError(err error) (*parser.Error, bool) {
+	for err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			return perr, true
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			return nil, false
+		}
+		err = causer.Cause()
+	}
+	return nil, false
+}
+
+// statementToken returns the start of the innermost statement in the err causes chain
+func statementToken(err error) *tokens.Token {
+	var token *tokens.Token
+	for err != nil {
+		if serr, ok := err.(*parser.StatementError); ok {
+			token = serr.Token
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = causer.Cause()
+	}
+	return token
+}
+
+// renderError converts an error raised while rendering node into a TemplateError
+// unless it already is one.
+func (r *Renderer) renderError(err error, node interface{ Position() *tokens.Token }) error {
+	if _, ok := AsTemplateError(err); ok {
+		return err
+	}
+	token := innermostToken(err)
+	if token == nil {
+		token = node.Position()
+	}
+	te := &TemplateError{
+		Name:    r.CurrentTemplate(),
+		Phase:   RenderPhase,
+		Message: err.Error(),
+		Stack:   append([]Frame{}, r.state.stack...),
+		Token:   token,
+		cause:   err,
+	}
+	if token != nil {
+		te.Line = token.Line
+		te.Column = token.Col
+	}
+	// Templates included dynamically are not known at parse time
+	if r.Template != nil && r.Template.Name == te.Name {
+		te.Source = r.Template.Source
+	}
+	return te
+}
@@ -0,0 +1,128 @@
+package exec
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/noirbizarre/gonja/nodes"
+	u "github.com/noirbizarre/gonja/utils"
+)
+
+// Escaper escapes a string for a given output format
+type Escaper func(in string) string
+
+// EscaperSet holds the escapers by name ("html", "js"...)
+type EscaperSet map[string]Escaper
+
+// Exists returns true if the given escaper is already registered
+func (es EscaperSet) Exists(name string) bool {
+	_, existing := es[name]
+	return existing
+}
+
+// Register registers a new escaper.
+// It returns an error if there's already an escaper with the same name.
+func (es *EscaperSet) Register(name string, fn Escaper) error {
+	if es.Exists(name) {
+		return errors.Errorf("escaper with name '%s' is already registered", name)
+	}
+	(*es)[name] = fn
+	return nil
+}
+
+// Replace replaces an already registered escaper with a new implementation.
+func (es *EscaperSet) Replace(name string, fn Escaper) error {
+	if !es.Exists(name) {
+		return errors.Errorf("escaper with name '%s' does not exist (therefore cannot be overridden)", name)
+	}
+	(*es)[name] = fn
+	return nil
+}
+
+func (es *EscaperSet) Update(other EscaperSet) EscaperSet {
+	for name, escaper := range other {
+		(*es)[name] = escaper
+	}
+	return *es
+}
+
+// AutoescapeSelector returns the name of the escaper to use by default
+// for the named template or an empty string to disable autoescaping.
+type AutoescapeSelector func(name string) string
+
+// DefaultAutoescapeExtensions maps the common template extensions to their escaper
+var DefaultAutoescapeExtensions = map[string]string{
+	"html":  "html",
+	"htm":   "html",
+	"xhtml": "html",
+	"xml":   "xml",
+	"svg":   "xml",
+	"js":    "js",
+	"css":   "css",
+	"json":  "json",
+	"txt":   "",
+}
+
+// SelectAutoescape returns an AutoescapeSelector choosing the escaper
+// from the template extension (without the leading dot, ie. "html"),
+// and using fallback for unknown extensions.
+// DefaultAutoescapeExtensions are used if extensions is nil.
+func SelectAutoescape(extensions map[string]string, fallback string) AutoescapeSelector {
+	if extensions == nil {
+		extensions = DefaultAutoescapeExtensions
+	}
+	return func(name string) string {
+		ext := strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+		if escaper, ok := extensions[ext]; ok {
+			return escaper
+		}
+		return fallback
+	}
+}
+
+// SelectAutoescape returns the autoescaping mode of the named template
+// or nil if there is no AutoescapeSelector.
+func (cfg *EvalConfig) SelectAutoescape(name string) *nodes.Autoescape {
+	if cfg.AutoescapeSelector == nil {
+		return nil
+	}
+	escaper := cfg.AutoescapeSelector(name)
+	return &nodes.Autoescape{Enabled: escaper != "", Escaper: escaper}
+}
+
+// AutoescapeTemplate sets the autoescaping mode selected for tpl, if any.
+func (cfg *EvalConfig) AutoescapeTemplate(tpl *nodes.Template) {
+	if tpl == nil || tpl.Autoescape == nil {
+		return
+	}
+	cfg.Autoescape = tpl.Autoescape.Enabled
+	if tpl.Autoescape.Escaper != "" {
+		cfg.Escaper = tpl.Autoescape.Escaper
+	}
+}
+
+// ForTemplate returns a renderer sharing the state of r
+// but rendering with the autoescaping mode selected for tpl.
+// r itself is returned if tpl has none.
+func (r *Renderer) ForTemplate(tpl *nodes.Template) *Renderer {
+	if tpl == nil || tpl.Autoescape == nil {
+		return r
+	}
+	sub := *r
+	sub.EvalConfig = r.EvalConfig.Inherit()
+	sub.AutoescapeTemplate(tpl)
+	return &sub
+}
+
+// Escape escapes in with the active escaper.
+// HTML escaping is used if none is set or if it is unknown.
+func (cfg *EvalConfig) Escape(in string) string {
+	if cfg.Escapers != nil && cfg.Escaper != "" {
+		if escaper, ok := (*cfg.Escapers)[cfg.Escaper]; ok {
+			return escaper(in)
+		}
+	}
+	return u.Escape(in)
+}
@@ -1,6 +1,7 @@
 package exec
 
 import (
+	"context"
 	"math"
 	"reflect"
 	"strings"
@@ -13,18 +14,36 @@ import (
 var (
 	typeOfValuePtr   = reflect.TypeOf(new(Value))
 	typeOfExecCtxPtr = reflect.TypeOf(new(Context))
+	typeOfStdCtx     = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeOfVarArgsPtr = reflect.TypeOf(new(VarArgs))
 )
 
+// Callable is implemented by values which are not functions
+// but can be called from templates (ie. recursive loops)
+type Callable interface {
+	Call(*VarArgs) *Value
+}
+
 type Evaluator struct {
 	*EvalConfig
-	Ctx *Context
+	Ctx    *Context
+	stdCtx context.Context
 }
 
 func (r *Renderer) Evaluator() *Evaluator {
 	return &Evaluator{
 		EvalConfig: r.EvalConfig,
 		Ctx:        r.Ctx,
+		stdCtx:     r.stdCtx,
+	}
+}
+
+// Context returns the context.Context the evaluation is bound to
+func (e *Evaluator) Context() context.Context {
+	if e.stdCtx == nil {
+		return context.Background()
 	}
+	return e.stdCtx
 }
 
 func (r *Renderer) Eval(node nodes.Expression) *Value {
@@ -58,11 +77,16 @@ func (e *Evaluator) Eval(node nodes.Expression) *Value {
 		return e.evalGetitem(n)
 	case *nodes.Getattr:
 		return e.evalGetattr(n)
+	case *nodes.Slice:
+		return e.evalSlice(n)
 	case *nodes.Negation:
 		result := e.Eval(n.Term)
 		if result.IsError() {
 			return result
 		}
+		if err := e.checkOperand(result, "not"); err != nil {
+			return AsValue(err)
+		}
 		return result.Negate()
 	case *nodes.BinaryExpression:
 		return e.evalBinaryExpression(n)
@@ -72,11 +96,31 @@ func (e *Evaluator) Eval(node nodes.Expression) *Value {
 		return e.EvaluateFiltered(n)
 	case *nodes.TestExpression:
 		return e.EvalTest(n)
+	case *nodes.InlineIfExpression:
+		return e.evalInlineIf(n)
 	default:
 		return AsValue(errors.Errorf(`Unknown expression type "%T"`, n))
 	}
 }
 
+func (e *Evaluator) evalInlineIf(node *nodes.InlineIfExpression) *Value {
+	condition := e.Eval(node.Condition)
+	if condition.IsError() {
+		return AsValue(errors.Wrapf(condition, `Unable to evaluate condition %s`, node.Condition))
+	}
+	isTrue, err := e.IsTrue(condition)
+	if err != nil {
+		return AsValue(err)
+	}
+	if isTrue {
+		return e.Eval(node.True)
+	}
+	if node.False == nil {
+		return e.undefined(node)
+	}
+	return e.Eval(node.False)
+}
+
 func (e *Evaluator) evalBinaryExpression(node *nodes.BinaryExpression) *Value {
 	var (
 		left  *Value
@@ -87,6 +131,10 @@ func (e *Evaluator) evalBinaryExpression(node *nodes.BinaryExpression) *Value {
 		return AsValue(errors.Wrapf(left, `Unable to evaluate left parameter %s`, node.Left))
 	}
 
+	if err := e.checkOperand(left, node.Operator.Token.Val); err != nil {
+		return AsValue(err)
+	}
+
 	switch node.Operator.Token.Val {
 	// These operators allow lazy right expression evluation
 	case "and", "or":
@@ -95,6 +143,9 @@ func (e *Evaluator) evalBinaryExpression(node *nodes.BinaryExpression) *Value {
 		if right.IsError() {
 			return AsValue(errors.Wrapf(right, `Unable to evaluate right parameter %s`, node.Right))
 		}
+		if err := e.checkOperand(right, node.Operator.Token.Val); err != nil {
+			return AsValue(err)
+		}
 	}
 
 	switch node.Operator.Token.Val {
@@ -151,6 +202,11 @@ func (e *Evaluator) evalBinaryExpression(node *nodes.BinaryExpression) *Value {
 	case "**":
 		return AsValue(math.Pow(left.Float(), right.Float()))
 	case "~":
+		for _, operand := range []*Value{left, right} {
+			if err := e.CheckString(operand, node.Operator.Token); err != nil {
+				return AsValue(err)
+			}
+		}
 		return AsValue(strings.Join([]string{left.String(), right.String()}, ""))
 	case "and":
 		if !left.IsTrue() {
@@ -208,6 +264,9 @@ func (e *Evaluator) evalUnaryExpression(expr *nodes.UnaryExpression) *Value {
 	if result.IsError() {
 		return AsValue(errors.Wrapf(result, `Unable to evaluate term %s`, expr.Term))
 	}
+	if err := e.checkOperand(result, expr.Operator.Val); err != nil {
+		return AsValue(err)
+	}
 	if expr.Negative {
 		if result.IsNumber() {
 			switch {
@@ -268,6 +327,9 @@ func (e *Evaluator) evalPair(node *nodes.Pair) *Value {
 }
 
 func (e *Evaluator) evalName(node *nodes.Name) *Value {
+	if !e.Ctx.Has(node.Name.Val) {
+		return e.undefined(node)
+	}
 	val := e.Ctx.Get(node.Name.Val)
 	return ToValue(val)
 }
@@ -278,40 +340,180 @@ func (e *Evaluator) evalGetitem(node *nodes.Getitem) *Value {
 		return AsValue(errors.Wrapf(value, `Unable to evaluate target %s`, node.Node))
 	}
 
-	if node.Arg != "" {
-		item, found := value.Getitem(node.Arg)
+	arg, index := node.Arg, node.Index
+	if node.Key != nil {
+		key := e.Eval(node.Key)
+		if key.IsError() {
+			return AsValue(errors.Wrapf(key, `Unable to evaluate item %s`, node.Key))
+		}
+		switch {
+		case key.IsString():
+			arg = key.String()
+		case key.IsInteger():
+			index = key.Integer()
+			if index < 0 && value.CanSlice() {
+				// Negative indexes count from the end, like in Python
+				index += value.Len()
+			}
+		case key.IsUndefined():
+			return e.undefined(node)
+		default:
+			return AsValue(errors.Errorf(`Unable to use %s as an item key`, key.String()))
+		}
+	}
+
+	if value.IsUndefined() {
+		return e.undefinedAttribute(value, node, arg, index)
+	}
+
+	if err := e.checkValue(value, node.Position()); err != nil {
+		return AsValue(err)
+	}
+
+	if arg != "" {
+		item, found := value.Getitem(arg)
 		if !found {
-			item, found = value.Getattr(node.Arg)
+			if err := e.checkAttribute(value, arg, node.Position()); err != nil {
+				return AsValue(err)
+			}
+			item, found = value.Getattr(arg)
 		}
 		if !found {
 			if item.IsError() {
 				return AsValue(errors.Wrapf(item, `Unable to evaluate %s`, node))
 			}
-			return AsValue(nil)
-			// return AsValue(errors.Errorf(`Unable to evaluate %s: item '%s' not found`, node, node.Arg))
+			return e.undefined(node)
 		}
 		return item
 	} else {
-		item, found := value.Getitem(node.Index)
+		item, found := value.Getitem(index)
 		if !found {
 			if item.IsError() {
 				return AsValue(errors.Wrapf(item, `Unable to evaluate %s`, node))
 			}
-			return AsValue(nil)
-			// return AsValue(errors.Errorf(`Unable to evaluate %s: item %d not found`, node, node.Index))
+			return e.undefined(node)
 		}
 		return item
 	}
 	return AsValue(errors.Errorf(`Unable to evaluate %s`, node))
 }
 
+func (e *Evaluator) evalSlice(node *nodes.Slice) *Value {
+	value := e.Eval(node.Node)
+	if value.IsError() {
+		return AsValue(errors.Wrapf(value, `Unable to evaluate target %s`, node.Node))
+	}
+
+	if err := e.checkValue(value, node.Position()); err != nil {
+		return AsValue(err)
+	}
+
+	if !value.CanSlice() {
+		return AsValue(errors.Errorf(`Unable to slice %s: %T is not sliceable`, node.Node, value.Interface()))
+	}
+
+	var bounds [3]*int
+	for idx, expr := range []nodes.Expression{node.Start, node.Stop, node.Step} {
+		if expr == nil {
+			continue
+		}
+		bound := e.Eval(expr)
+		if bound.IsError() {
+			return AsValue(errors.Wrapf(bound, `Unable to evaluate slice bound %s`, expr))
+		}
+		if bound.IsNil() {
+			continue
+		}
+		if !bound.IsInteger() {
+			return AsValue(errors.Errorf(`Slice indices must be integers, got %s`, bound.String()))
+		}
+		i := bound.Integer()
+		bounds[idx] = &i
+	}
+
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return AsValue(errors.New(`Slice step cannot be zero`))
+	}
+
+	resolved := value.getResolvedValue()
+	if resolved.Kind() == reflect.String {
+		runes := []rune(resolved.String())
+		indices := sliceIndices(len(runes), bounds[0], bounds[1], step)
+		out := make([]rune, 0, len(indices))
+		for _, i := range indices {
+			out = append(out, runes[i])
+		}
+		return AsValue(string(out))
+	}
+
+	indices := sliceIndices(resolved.Len(), bounds[0], bounds[1], step)
+	out := reflect.MakeSlice(reflect.SliceOf(resolved.Type().Elem()), 0, len(indices))
+	for _, i := range indices {
+		out = reflect.Append(out, resolved.Index(i))
+	}
+	return AsValue(out.Interface())
+}
+
+// sliceIndices computes the indices selected by a slice
+// on a sequence of the given length, the python way:
+// negative bounds are relative to the end and out of range ones are clamped.
+func sliceIndices(length int, start, stop *int, step int) []int {
+	lower, upper := 0, length
+	if step < 0 {
+		lower, upper = -1, length-1
+	}
+	bound := func(value *int, fallback int) int {
+		if value == nil {
+			return fallback
+		}
+		i := *value
+		if i < 0 {
+			i += length
+			if i < lower {
+				i = lower
+			}
+		} else if i > upper {
+			i = upper
+		}
+		return i
+	}
+
+	var first, last int
+	if step > 0 {
+		first, last = bound(start, lower), bound(stop, upper)
+	} else {
+		first, last = bound(start, upper), bound(stop, lower)
+	}
+
+	indices := []int{}
+	for i := first; (step > 0 && i < last) || (step < 0 && i > last); i += step {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
 func (e *Evaluator) evalGetattr(node *nodes.Getattr) *Value {
 	value := e.Eval(node.Node)
 	if value.IsError() {
 		return AsValue(errors.Wrapf(value, `Unable to evaluate target %s`, node.Node))
 	}
 
+	if value.IsUndefined() {
+		return e.undefinedAttribute(value, node, node.Attr, node.Index)
+	}
+
+	if err := e.checkValue(value, node.Position()); err != nil {
+		return AsValue(err)
+	}
+
 	if node.Attr != "" {
+		if err := e.checkAttribute(value, node.Attr, node.Position()); err != nil {
+			return AsValue(err)
+		}
 		attr, found := value.Getattr(node.Attr)
 		if !found {
 			attr, found = value.Getitem(node.Attr)
@@ -320,8 +522,7 @@ func (e *Evaluator) evalGetattr(node *nodes.Getattr) *Value {
 			if attr.IsError() {
 				return AsValue(errors.Wrapf(attr, `Unable to evaluate %s`, node))
 			}
-			return AsValue(nil)
-			// return AsValue(errors.Errorf(`Unable to evaluate %s: attribute '%s' not found`, node, node.Attr))
+			return e.undefined(node)
 		}
 		return attr
 	} else {
@@ -330,8 +531,7 @@ func (e *Evaluator) evalGetattr(node *nodes.Getattr) *Value {
 			if item.IsError() {
 				return AsValue(errors.Wrapf(item, `Unable to evaluate %s`, node))
 			}
-			return AsValue(nil)
-			// return AsValue(errors.Errorf(`Unable to evaluate %s: item %d not found`, node, node.Index))
+			return e.undefined(node)
 		}
 		return item
 	}
@@ -343,6 +543,18 @@ func (e *Evaluator) evalCall(node *nodes.Call) *Value {
 	if fn.IsError() {
 		return AsValue(errors.Wrapf(fn, `Unable to evaluate function "%s"`, node.Func))
 	}
+	if fn.IsUndefined() {
+		return AsValue(fn.undefined.Err())
+	}
+
+	if err := e.checkValue(fn, node.Position()); err != nil {
+		return AsValue(err)
+	}
+	if fn.Val.IsValid() && fn.Val.CanInterface() {
+		if callable, ok := fn.Interface().(Callable); ok {
+			fn = AsValue(callable.Call)
+		}
+	}
 
 	if !fn.IsCallable() {
 		return AsValue(errors.Errorf(`%s is not callable`, node.Func))
@@ -357,14 +569,23 @@ func (e *Evaluator) evalCall(node *nodes.Call) *Value {
 	var err error
 	t := fn.Val.Type()
 
-	if t.NumIn() == 1 && t.In(0) == reflect.TypeOf(&VarArgs{}) {
+	// Functions expecting a context.Context as first argument receive the rendering one
+	offset := 0
+	if t.NumIn() > 0 && t.In(0) == typeOfStdCtx {
+		offset = 1
+	}
+
+	if t.NumIn() == offset+1 && t.In(offset) == typeOfVarArgsPtr {
 		params, err = e.evalVarArgs(node)
 	} else {
-		params, err = e.evalParams(node, fn)
+		params, err = e.evalParams(node, fn, offset)
 	}
 	if err != nil {
 		return AsValue(errors.Wrapf(err, `Unable to evaluate parameters`))
 	}
+	if offset > 0 {
+		params = append([]reflect.Value{reflect.ValueOf(e.Context())}, params...)
+	}
 
 	// Call it and get first return parameter back
 	values := fn.Val.Call(params)
@@ -560,13 +781,14 @@ func (e *Evaluator) evalVarArgs(node *nodes.Call) ([]reflect.Value, error) {
 	return []reflect.Value{reflect.ValueOf(params)}, nil
 }
 
-func (e *Evaluator) evalParams(node *nodes.Call, fn *Value) ([]reflect.Value, error) {
+func (e *Evaluator) evalParams(node *nodes.Call, fn *Value, offset int) ([]reflect.Value, error) {
 	args := node.Args
 	t := fn.Val.Type()
+	numArgs := t.NumIn() - offset
 
-	if len(args) != t.NumIn() && !(len(args) >= t.NumIn()-1 && t.IsVariadic()) {
+	if len(args) != numArgs && !(len(args) >= numArgs-1 && t.IsVariadic()) {
 		msg := "Function input argument count (%d) of '%s' must be equal to the calling argument count (%d)."
-		return nil, errors.Errorf(msg, t.NumIn(), node.String(), len(args))
+		return nil, errors.Errorf(msg, numArgs, node.String(), len(args))
 	}
 
 	// Output arguments
@@ -578,7 +800,6 @@ func (e *Evaluator) evalParams(node *nodes.Call, fn *Value) ([]reflect.Value, er
 	// Evaluate all parameters
 	var parameters []reflect.Value
 
-	numArgs := t.NumIn()
 	isVariadic := t.IsVariadic()
 	var fnArg reflect.Type
 
@@ -590,12 +811,12 @@ func (e *Evaluator) evalParams(node *nodes.Call, fn *Value) ([]reflect.Value, er
 
 		if isVariadic {
 			if idx >= numArgs-1 {
-				fnArg = t.In(numArgs - 1).Elem()
+				fnArg = t.In(t.NumIn() - 1).Elem()
 			} else {
-				fnArg = t.In(idx)
+				fnArg = t.In(idx + offset)
 			}
 		} else {
-			fnArg = t.In(idx)
+			fnArg = t.In(idx + offset)
 		}
 
 		if fnArg != typeOfValuePtr {
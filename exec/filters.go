@@ -68,6 +68,14 @@ func (e *Evaluator) EvaluateFiltered(expr *nodes.FilteredExpression) *Value {
 
 // ExecuteFilter execute a filter node
 func (e *Evaluator) ExecuteFilter(fc *nodes.FilterCall, v *Value) *Value {
+	if e.Policy != nil && !e.Policy.IsSafeFilter(fc.Name) {
+		return AsValue(securityError(fc.Token, `Filter "%s" is not allowed`, fc.Name))
+	}
+	if fc.Name != "default" && fc.Name != "d" {
+		if err := e.checkOperand(v, "filter"); err != nil {
+			return AsValue(err)
+		}
+	}
 	params := NewVarArgs()
 
 	for _, param := range fc.Args {
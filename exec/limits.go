@@ -0,0 +1,142 @@
+package exec
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/noirbizarre/gonja/tokens"
+)
+
+// Limits bounds the resources a template rendering can consume.
+// A zero value means unlimited.
+type Limits struct {
+	// MaxOutputBytes is the maximum number of bytes rendered, written to the output
+	// or to the buffers of macro calls, set and filter blocks
+	MaxOutputBytes int64
+	// MaxLoopIterations is the maximum number of loop iterations for a whole rendering
+	MaxLoopIterations int64
+	// MaxRecursionDepth is the maximum depth of nested macro calls, includes and recursive loops
+	MaxRecursionDepth int
+	// Timeout is the maximum duration of a rendering
+	Timeout time.Duration
+}
+
+// OutputLimitError is returned when the rendered output exceeds Limits.MaxOutputBytes
+type OutputLimitError struct {
+	Limit int64
+}
+
+func (e *OutputLimitError) Error() string {
+	return fmt.Sprintf("Output limit exceeded: more than %d bytes rendered", e.Limit)
+}
+
+// LoopLimitError is returned when the rendering exceeds Limits.MaxLoopIterations
+type LoopLimitError struct {
+	Limit int64
+}
+
+func (e *LoopLimitError) Error() string {
+	return fmt.Sprintf("Loop limit exceeded: more than %d iterations", e.Limit)
+}
+
+// RecursionLimitError is returned when macro calls or includes nest deeper than Limits.MaxRecursionDepth
+type RecursionLimitError struct {
+	Limit int
+	Name  string // The macro or template being entered
+}
+
+func (e *RecursionLimitError) Error() string {
+	return fmt.Sprintf("Recursion limit exceeded: more than %d nested calls entering '%s'", e.Limit, e.Name)
+}
+
+// TimeLimitError is returned when the rendering lasts longer than Limits.Timeout
+type TimeLimitError struct {
+	Limit time.Duration
+}
+
+func (e *TimeLimitError) Error() string {
+	return fmt.Sprintf("Time limit exceeded: rendering took more than %s", e.Limit)
+}
+
+// outputBudget counts the bytes rendered against Limits.MaxOutputBytes
+type outputBudget struct {
+	limit   int64
+	written int64
+}
+
+// spend accounts for n rendered bytes and fails if the limit is exceeded
+func (b *outputBudget) spend(n int) error {
+	b.written += int64(n)
+	if b.limit > 0 && b.written > b.limit {
+		return &OutputLimitError{Limit: b.limit}
+	}
+	return nil
+}
+
+// renderState holds the counters shared by all renderers of a single rendering
+type renderState struct {
+	iterations int64
+	stack      []Frame
+	depth      int           // The number of recursive calls in the stack
+	budget     *outputBudget // The output bytes budget, if limited
+	err        error         // First output error, stops the rendering
+}
+
+// Iterate accounts for a loop iteration and fails if Limits.MaxLoopIterations is exceeded
+func (r *Renderer) Iterate() error {
+	r.state.iterations++
+	if max := r.Limits.MaxLoopIterations; max > 0 && r.state.iterations > max {
+		return &LoopLimitError{Limit: max}
+	}
+	return nil
+}
+
+// isRecursive returns true if the calls of kind can recurse
+// and count against Limits.MaxRecursionDepth
+func isRecursive(kind string) bool {
+	return kind == "macro" || kind == "include" || kind == "loop"
+}
+
+// Enter pushes a call (macro, include, block...) of name to the call stack.
+// body is the name of the template holding the called body.
+// It fails if Limits.MaxRecursionDepth is exceeded by macro calls,
+// includes and recursive loops, the other calls are only used for error traces.
+// Each successful Enter call must be followed by a Leave call.
+func (r *Renderer) Enter(kind, name, body string, token *tokens.Token) error {
+	if isRecursive(kind) {
+		if max := r.Limits.MaxRecursionDepth; max > 0 && r.state.depth >= max {
+			return &RecursionLimitError{Limit: max, Name: name}
+		}
+		r.state.depth++
+	}
+	r.state.stack = append(r.state.stack, Frame{
+		Kind:     kind,
+		Name:     name,
+		Template: r.CurrentTemplate(),
+		Token:    token,
+		body:     body,
+	})
+	return nil
+}
+
+// Leave pops the last call from the call stack
+func (r *Renderer) Leave() {
+	last := len(r.state.stack) - 1
+	if isRecursive(r.state.stack[last].Kind) {
+		r.state.depth--
+	}
+	r.state.stack = r.state.stack[:last]
+}
+
+// ResolveTemplate resolves a template name loaded from the template being rendered
+func (r *Renderer) ResolveTemplate(name string) string {
+	return r.EvalConfig.ResolveTemplate(r.CurrentTemplate(), name)
+}
+
+// CurrentTemplate returns the name of the template being rendered
+func (r *Renderer) CurrentTemplate() string {
+	if size := len(r.state.stack); size > 0 {
+		return r.state.stack[size-1].body
+	}
+	return r.Template.Name
+}
@@ -57,6 +57,9 @@ func MacroNodeToFunc(node *nodes.Macro, r *Renderer) (Macro, error) {
 		defaultKwargs = append(defaultKwargs, &KwArg{key, value.Interface()})
 	}
 
+	// The template holding the macro body
+	body := r.CurrentTemplate()
+
 	return func(params *VarArgs) *Value {
 		var out strings.Builder
 		sub := r.Inherit()
@@ -65,6 +68,24 @@ func MacroNodeToFunc(node *nodes.Macro, r *Renderer) (Macro, error) {
 		if err != nil {
 			return AsValue(err)
 		}
+		if err := r.Interrupted(); err != nil {
+			return AsValue(errors.Wrapf(err, `Unable to execute macro '%s'`, node.Name))
+		}
+		if err := r.Enter("macro", node.Name, body, nil); err != nil {
+			return AsValue(errors.Wrapf(err, `Unable to execute macro '%s'`, node.Name))
+		}
+		defer r.Leave()
+		// The caller given by a {% call %} block is not part of the signature
+		if caller, ok := params.KwArgs["caller"]; ok {
+			kwargs := map[string]*Value{}
+			for key, value := range params.KwArgs {
+				if key != "caller" {
+					kwargs[key] = value
+				}
+			}
+			params = &VarArgs{Args: params.Args, KwArgs: kwargs}
+			sub.Ctx.Set("caller", caller)
+		}
 		p := params.Expect(len(node.Args), defaultKwargs)
 		if p.IsError() {
 			return AsValue(errors.Wrapf(p, `Wrong '%s' macro signature`, node.Name))
@@ -77,7 +98,7 @@ func MacroNodeToFunc(node *nodes.Macro, r *Renderer) (Macro, error) {
 		}
 		err := sub.ExecuteWrapper(node.Wrapper)
 		if err != nil {
-			return AsValue(errors.Wrapf(err, `Unable to execute macro '%s`, node.Name))
+			return AsValue(errors.Wrapf(err, `Unable to execute macro '%s'`, node.Name))
 		}
 		return AsSafeValue(out.String())
 	}, err
@@ -1,6 +1,9 @@
 package exec
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -15,19 +18,57 @@ type TrimState struct {
 	Buffer      *strings.Builder
 }
 
-func (ts *TrimState) TrimBlocks(r rune) bool {
-	if ts.ShouldBlock {
-		switch r {
-		case '\n':
-			ts.ShouldBlock = false
-			return true
-		case ' ', '\t':
-			return true
-		default:
-			return false
-		}
+// TrimBlocks removes the first newline (and the blanks preceding it)
+// following a block if required.
+func (ts *TrimState) TrimBlocks(txt string) string {
+	if !ts.ShouldBlock {
+		return txt
+	}
+	txt = strings.TrimLeft(txt, " \t")
+	if nl := leadingNewline(txt); nl > 0 {
+		ts.ShouldBlock = false
+		txt = txt[nl:]
 	}
-	return false
+	return txt
+}
+
+// leadingNewline returns the length of the newline sequence
+// ('\r\n', '\n' or '\r') starting txt, 0 if none.
+func leadingNewline(txt string) int {
+	switch {
+	case strings.HasPrefix(txt, "\r\n"):
+		return 2
+	case strings.HasPrefix(txt, "\n"), strings.HasPrefix(txt, "\r"):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// trailingNewline returns the length of the newline sequence
+// ('\r\n', '\n' or '\r') ending txt, 0 if none.
+func trailingNewline(txt string) int {
+	switch {
+	case strings.HasSuffix(txt, "\r\n"):
+		return 2
+	case strings.HasSuffix(txt, "\n"), strings.HasSuffix(txt, "\r"):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// normalizeNewlines replaces all newline sequences found in txt by nl
+func normalizeNewlines(txt string, nl string) string {
+	if nl == "" || !strings.ContainsAny(txt, "\r\n") {
+		return txt
+	}
+	txt = strings.ReplaceAll(txt, "\r\n", "\n")
+	txt = strings.ReplaceAll(txt, "\r", "\n")
+	if nl != "\n" {
+		txt = strings.ReplaceAll(txt, "\n", nl)
+	}
+	return txt
 }
 
 // Renderer is a node visitor in charge of rendering
@@ -36,12 +77,14 @@ type Renderer struct {
 	Ctx      *Context
 	Template *Template
 	Root     *nodes.Template
-	Out      *strings.Builder
+	Out      io.StringWriter
 	Trim     *TrimState
+	stdCtx   context.Context
+	state    *renderState
 }
 
 // NewRenderer initialize a new renderer
-func NewRenderer(ctx *Context, out *strings.Builder, cfg *EvalConfig, tpl *Template) *Renderer {
+func NewRenderer(ctx *Context, out io.StringWriter, cfg *EvalConfig, tpl *Template) *Renderer {
 	var buffer strings.Builder
 	r := &Renderer{
 		EvalConfig: cfg,
@@ -50,6 +93,8 @@ func NewRenderer(ctx *Context, out *strings.Builder, cfg *EvalConfig, tpl *Templ
 		Root:       tpl.Root,
 		Out:        out,
 		Trim:       &TrimState{Buffer: &buffer},
+		stdCtx:     context.Background(),
+		state:      &renderState{},
 	}
 	r.Ctx.Set("self", Self(r))
 	return r
@@ -64,10 +109,29 @@ func (r *Renderer) Inherit() *Renderer {
 		Root:       r.Root,
 		Out:        r.Out,
 		Trim:       r.Trim,
+		stdCtx:     r.stdCtx,
+		state:      r.state,
 	}
 	return sub
 }
 
+// Context returns the context.Context the rendering is bound to
+func (r *Renderer) Context() context.Context {
+	if r.stdCtx == nil {
+		return context.Background()
+	}
+	return r.stdCtx
+}
+
+// Interrupted returns a non-nil error if the rendering has been cancelled
+// or its deadline exceeded.
+func (r *Renderer) Interrupted() error {
+	if err := r.Context().Err(); err != nil {
+		return errors.Wrap(err, `Rendering interrupted`)
+	}
+	return nil
+}
+
 func (r *Renderer) Flush(lstrip bool) {
 	r.FlushAndTrim(false, lstrip)
 }
@@ -75,25 +139,34 @@ func (r *Renderer) Flush(lstrip bool) {
 func (r *Renderer) FlushAndTrim(trim, lstrip bool) {
 	txt := r.Trim.Buffer.String()
 	if r.Config.LstripBlocks && !lstrip {
-		lines := strings.Split(txt, "\n")
-		last := lines[len(lines)-1]
-		lines[len(lines)-1] = strings.TrimLeft(last, " \t")
-		txt = strings.Join(lines, "\n")
+		idx := strings.LastIndexAny(txt, "\r\n") + 1
+		txt = txt[:idx] + strings.TrimLeft(txt[idx:], " \t")
 	}
 	if trim {
-		txt = strings.TrimRight(txt, " \t\n")
+		txt = strings.TrimRight(txt, " \t\r\n")
 	}
-	r.Out.WriteString(txt)
 	r.Trim.Buffer.Reset()
+	if _, final := r.Out.(*outputWriter); !final && r.state.budget != nil {
+		// The final output is accounted by its writer, buffers are accounted here
+		if err := r.state.budget.spend(len(txt)); err != nil {
+			if r.state.err == nil {
+				r.state.err = err
+			}
+			return
+		}
+	}
+	if _, err := r.Out.WriteString(txt); err != nil && r.state.err == nil {
+		r.state.err = err
+	}
 }
 
 // WriteString wraps the triming policy
 func (r *Renderer) WriteString(txt string) (int, error) {
 	if r.Config.TrimBlocks {
-		txt = strings.TrimLeftFunc(txt, r.Trim.TrimBlocks)
+		txt = r.Trim.TrimBlocks(txt)
 	}
 	if r.Trim.Should {
-		txt = strings.TrimLeft(txt, " \t\n")
+		txt = strings.TrimLeft(txt, " \t\r\n")
 		if len(txt) > 0 {
 			r.Trim.Should = false
 		}
@@ -104,7 +177,7 @@ func (r *Renderer) WriteString(txt string) (int, error) {
 // RenderValue properly render a value
 func (r *Renderer) RenderValue(value *Value) {
 	if r.Autoescape && value.IsString() && !value.Safe {
-		r.WriteString(value.Escaped())
+		r.WriteString(r.Escape(value.String()))
 	} else {
 		r.WriteString(value.String())
 	}
@@ -133,32 +206,53 @@ func (r *Renderer) Tag(trim *nodes.Trim, lstrip bool) {
 
 // Visit implements the nodes.Visitor interface
 func (r *Renderer) Visit(node nodes.Node) (nodes.Visitor, error) {
+	if r.state.err != nil {
+		return nil, r.state.err
+	}
 	switch n := node.(type) {
 	case *nodes.Comment:
 		r.Tag(n.Trim, false)
 		return nil, nil
 	case *nodes.Data:
-		r.WriteString(n.Data.Val)
+		r.WriteString(normalizeNewlines(n.Data.Val, r.Config.NewlineSequence))
 		return nil, nil
 	case *nodes.Output:
 		r.StartTag(n.Trim, false)
 		value := r.Eval(n.Expression)
 		if value.IsError() {
-			return nil, errors.Wrapf(value, `Unable to render expression '%s'`, n.Expression)
+			return nil, r.renderError(errors.Wrapf(value, `Unable to render expression '%s'`, n.Expression), n.Expression)
+		}
+		if value.IsUndefined() {
+			printed, err := r.Evaluator().undefinedPolicy().Print(value.undefined)
+			if err != nil {
+				return nil, r.renderError(errors.Wrapf(err, `Unable to render expression '%s'`, n.Expression), n.Expression)
+			}
+			value = AsValue(printed)
+		}
+		if err := r.Evaluator().CheckString(value, n.Expression.Position()); err != nil {
+			return nil, r.renderError(err, n.Expression)
 		}
 		r.RenderValue(value)
 		r.EndTag(n.Trim)
 		return nil, nil
 	case *nodes.StatementBlock:
+		if r.Policy != nil && !r.Policy.IsSafeStatement(n.Name) {
+			return nil, r.renderError(securityError(n.Location, `Statement "%s" is not allowed`, n.Name), n)
+		}
 		r.Tag(n.Trim, n.LStrip)
-		r.Trim.ShouldBlock = r.Config.TrimBlocks
+		// Line statements always consume their trailing newline
+		r.Trim.ShouldBlock = r.Config.TrimBlocks && !n.LineStatement
 		stmt, ok := n.Stmt.(Statement)
 		if ok {
 			// Silently ignore non executable statements
 			// return nil, nil
 			// return nil, errors.Errorf(`Unable to execute statement '%s'`, n.Stmt)
 			if err := stmt.Execute(r, n); err != nil {
-				return nil, errors.Wrapf(err, `Unable to execute statement '%s'`, n.Stmt)
+				if IsLoopControl(err) {
+					// Propagated as is up to the enclosing loop
+					return nil, err
+				}
+				return nil, r.renderError(errors.Wrapf(err, `Unable to execute statement '%s'`, n.Stmt), n)
 			}
 		}
 		return nil, nil
@@ -170,9 +264,9 @@ func (r *Renderer) Visit(node nodes.Node) (nodes.Visitor, error) {
 // ExecuteWrapper wraps the nodes.Wrapper execution logic
 func (r *Renderer) ExecuteWrapper(wrapper *nodes.Wrapper) error {
 	sub := r.Inherit()
-	err := nodes.Walk(sub, wrapper)
+	err := nodes.WalkContext(r.Context(), sub, wrapper)
 	sub.Tag(wrapper.Trim, wrapper.LStrip)
-	r.Trim.ShouldBlock = r.Config.TrimBlocks
+	r.Trim.ShouldBlock = r.Config.TrimBlocks && !wrapper.LineStatement
 	return err
 }
 
@@ -186,18 +280,32 @@ func (r *Renderer) Execute() error {
 		root = root.Parent
 	}
 
-	err := nodes.Walk(r, root)
+	if root != r.Root {
+		if err := r.Enter("extends", root.Name, root.Name, nil); err != nil {
+			return err
+		}
+		defer r.Leave()
+	}
+	if root.Autoescape != nil {
+		// The nodes are escaped according to the template they belong to
+		r.EvalConfig = r.EvalConfig.Inherit()
+		r.AutoescapeTemplate(root)
+	}
+
+	err := nodes.WalkContext(r.Context(), r, root)
 	if err == nil {
 		r.Flush(false)
+		// The output may have failed after the last node
+		err = r.state.err
 	}
 	return err
 }
 
+// String returns the rendered output if the output supports it
 func (r *Renderer) String() string {
 	r.Flush(false)
-	out := r.Out.String()
-	if !r.Config.KeepTrailingNewline {
-		out = strings.TrimSuffix(out, "\n")
+	if stringer, ok := r.Out.(fmt.Stringer); ok {
+		return stringer.String()
 	}
-	return out
+	return ""
 }
@@ -0,0 +1,387 @@
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/noirbizarre/gonja/tokens"
+)
+
+// SecurityPolicy decides what a template is allowed to access
+// when rendered in a sandboxed environment.
+type SecurityPolicy interface {
+	// IsSafeType returns true if the values of type t can be accessed
+	// (attributes, items) or called
+	IsSafeType(t reflect.Type) bool
+	// IsSafeField returns true if field can be read on values of type t
+	IsSafeField(t reflect.Type, field reflect.StructField) bool
+	// IsSafeMethod returns true if method can be accessed on values of type t
+	IsSafeMethod(t reflect.Type, method reflect.Method) bool
+	// IsSafeFilter returns true if the filter name can be used
+	IsSafeFilter(name string) bool
+	// IsSafeStatement returns true if the statement name can be used
+	IsSafeStatement(name string) bool
+}
+
+// SecurityError is raised when a template tries to access something
+// denied by the SecurityPolicy.
+type SecurityError struct {
+	Message string
+	Token   *tokens.Token // The position in the template, if known
+}
+
+func (e *SecurityError) Error() string {
+	if e.Token == nil {
+		return e.Message
+	}
+	return fmt.Sprintf(`%s (Line: %d Col: %d, near "%s")`, e.Message, e.Token.Line, e.Token.Col, e.Token.Val)
+}
+
+// Position returns the position in the template, if known
+func (e *SecurityError) Position() *tokens.Token {
+	return e.Token
+}
+
+// IsSecurityError returns true if err is (or has been caused by) a SecurityError
+func IsSecurityError(err error) bool {
+	_, ok := errors.Cause(err).(*SecurityError)
+	return ok
+}
+
+func securityError(token *tokens.Token, format string, args ...interface{}) *SecurityError {
+	return &SecurityError{
+		Message: fmt.Sprintf(format, args...),
+		Token:   token,
+	}
+}
+
+// checkValue ensures the policy allows accessing value
+func (e *Evaluator) checkValue(value *Value, token *tokens.Token) error {
+	if e.Policy == nil || !value.Val.IsValid() {
+		return nil
+	}
+	if t := value.Val.Type(); !e.Policy.IsSafeType(t) {
+		return securityError(token, `Access to type "%s" is not allowed`, t)
+	}
+	return nil
+}
+
+// checkAttribute ensures the policy allows accessing the attribute name of value.
+// Attributes are resolved the same way Value.Getattr does.
+func (e *Evaluator) checkAttribute(value *Value, name string, token *tokens.Token) error {
+	if e.Policy == nil || !value.Val.IsValid() {
+		return nil
+	}
+	if err := e.checkValue(value, token); err != nil {
+		return err
+	}
+	t := value.Val.Type()
+	if method, ok := t.MethodByName(name); ok {
+		if !e.Policy.IsSafeMethod(t, method) {
+			return securityError(token, `Access to method "%s" of type "%s" is not allowed`, name, t)
+		}
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		if field, ok := t.FieldByName(name); ok && !e.Policy.IsSafeField(t, field) {
+			return securityError(token, `Access to field "%s" of type "%s" is not allowed`, name, t)
+		}
+	}
+	return nil
+}
+
+// CheckSetattr ensures the policy allows setting the attribute or item name of value.
+// Like in Jinja's sandbox, only namespaces and dicts can be modified.
+func (e *Evaluator) CheckSetattr(value *Value, name string, token *tokens.Token) error {
+	if e.Policy == nil || !value.Val.IsValid() {
+		return nil
+	}
+	if err := e.checkAttribute(value, name, token); err != nil {
+		return err
+	}
+	if t := baseType(value.Val.Type()); t.Kind() != reflect.Map && t != TypeDict {
+		return securityError(token, `Assignment to "%s" of type "%s" is not allowed`, name, value.Val.Type())
+	}
+	return nil
+}
+
+var typeJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+var typeStringer = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// CheckString ensures the policy allows converting value to a string.
+// Structs are converted by their String method, if any,
+// which must be allowed, including for the items of lists and dicts.
+func (e *Evaluator) CheckString(value *Value, token *tokens.Token) error {
+	if e.Policy == nil {
+		return nil
+	}
+	return e.checkString(value.Val, token, map[uintptr]bool{})
+}
+
+// checkString walks val the way Value.String does
+// and checks every String method called against the policy.
+func (e *Evaluator) checkString(val reflect.Value, token *tokens.Token, seen map[uintptr]bool) error {
+	if !val.IsValid() {
+		return nil
+	}
+	if val.Kind() == reflect.Interface {
+		return e.checkString(val.Elem(), token, seen)
+	}
+	if val.Type() == reflect.TypeOf(&Value{}) {
+		if val.IsNil() {
+			return nil
+		}
+		return e.checkString(val.Interface().(*Value).Val, token, seen)
+	}
+	resolved := val
+	for resolved.Kind() == reflect.Ptr {
+		if resolved.IsNil() || seen[resolved.Pointer()] {
+			return nil
+		}
+		seen[resolved.Pointer()] = true
+		resolved = resolved.Elem()
+	}
+	switch resolved.Kind() {
+	case reflect.Struct:
+		if resolved.Type() == TypeDict {
+			for _, pair := range resolved.Interface().(Dict).Pairs {
+				if err := e.checkString(reflect.ValueOf(pair.Value), token, seen); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if t := val.Type(); t.Implements(typeStringer) {
+			method, _ := t.MethodByName("String")
+			if !e.Policy.IsSafeMethod(t, method) {
+				return securityError(token, `Access to method "String" of type "%s" is not allowed`, t)
+			}
+		}
+	case reflect.Map:
+		iter := resolved.MapRange()
+		for iter.Next() {
+			if err := e.checkString(iter.Value(), token, seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < resolved.Len(); idx++ {
+			if err := e.checkString(resolved.Index(idx), token, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckSerializable ensures the policy allows reading everything value holds
+// before it is serialized as a whole (ie. by the "tojson" filter).
+func (e *Evaluator) CheckSerializable(value *Value) error {
+	if e.Policy == nil {
+		return nil
+	}
+	return e.checkTree(value.Val, map[uintptr]bool{})
+}
+
+// checkTree walks val the way encoding/json does and checks every type,
+// field and custom marshaler against the policy.
+func (e *Evaluator) checkTree(val reflect.Value, seen map[uintptr]bool) error {
+	if !val.IsValid() {
+		return nil
+	}
+	t := val.Type()
+	switch t.Kind() {
+	case reflect.Interface:
+		return e.checkTree(val.Elem(), seen)
+	case reflect.Ptr:
+		if val.IsNil() {
+			return nil
+		}
+		if seen[val.Pointer()] {
+			return nil
+		}
+		seen[val.Pointer()] = true
+		if t == reflect.TypeOf(&Value{}) {
+			return e.checkTree(val.Interface().(*Value).Val, seen)
+		}
+	}
+	if !e.Policy.IsSafeType(t) {
+		return securityError(nil, `Access to type "%s" is not allowed`, t)
+	}
+	if t.Implements(typeJSONMarshaler) {
+		method, _ := t.MethodByName("MarshalJSON")
+		if !e.Policy.IsSafeMethod(t, method) {
+			return securityError(nil, `Access to method "MarshalJSON" of type "%s" is not allowed`, t)
+		}
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return e.checkTree(val.Elem(), seen)
+	case reflect.Struct:
+		for idx := 0; idx < t.NumField(); idx++ {
+			field := t.Field(idx)
+			if field.PkgPath != "" && !field.Anonymous || field.Tag.Get("json") == "-" {
+				// Not serialized
+				continue
+			}
+			if !e.Policy.IsSafeField(t, field) {
+				return securityError(nil, `Access to field "%s" of type "%s" is not allowed`, field.Name, t)
+			}
+			if err := e.checkTree(val.Field(idx), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := val.MapRange()
+		for iter.Next() {
+			if err := e.checkTree(iter.Value(), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < val.Len(); idx++ {
+			if err := e.checkTree(val.Index(idx), seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Getattr returns the attribute name of value if allowed by the security policy
+func (e *Evaluator) Getattr(value *Value, name string) (*Value, bool) {
+	if err := e.checkAttribute(value, name, nil); err != nil {
+		return AsValue(err), false
+	}
+	return value.Getattr(name)
+}
+
+// Get returns the attribute or the item key of value if allowed by the security policy
+func (e *Evaluator) Get(value *Value, key string) (*Value, bool) {
+	if err := e.checkAttribute(value, key, nil); err != nil {
+		return AsValue(err), false
+	}
+	return value.Get(key)
+}
+
+// SandboxPolicy is a SecurityPolicy built from allow and deny lists.
+// Every exported field is readable unless denied
+// while only the whitelisted methods and gonja's own ones are accessible.
+// Types are registered from sample values and pointers are dereferenced
+// so Foo{} and &Foo{} are equivalent.
+type SandboxPolicy struct {
+	DeniedTypes      map[reflect.Type]bool
+	DeniedFields     map[reflect.Type]map[string]bool
+	AllowedMethods   map[reflect.Type]map[string]bool
+	DeniedFilters    map[string]bool
+	DeniedStatements map[string]bool
+}
+
+// NewSandboxPolicy creates an empty SandboxPolicy
+func NewSandboxPolicy() *SandboxPolicy {
+	return &SandboxPolicy{
+		DeniedTypes:      map[reflect.Type]bool{},
+		DeniedFields:     map[reflect.Type]map[string]bool{},
+		AllowedMethods:   map[reflect.Type]map[string]bool{},
+		DeniedFilters:    map[string]bool{},
+		DeniedStatements: map[string]bool{},
+	}
+}
+
+func baseType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// DenyTypes denies any access to values of the same types as samples
+func (p *SandboxPolicy) DenyTypes(samples ...interface{}) *SandboxPolicy {
+	for _, sample := range samples {
+		p.DeniedTypes[baseType(reflect.TypeOf(sample))] = true
+	}
+	return p
+}
+
+// DenyFields denies reading the given fields on values of the same type as sample
+func (p *SandboxPolicy) DenyFields(sample interface{}, names ...string) *SandboxPolicy {
+	t := baseType(reflect.TypeOf(sample))
+	if p.DeniedFields[t] == nil {
+		p.DeniedFields[t] = map[string]bool{}
+	}
+	for _, name := range names {
+		p.DeniedFields[t][name] = true
+	}
+	return p
+}
+
+// AllowMethods allows accessing the given methods on values of the same type as sample
+func (p *SandboxPolicy) AllowMethods(sample interface{}, names ...string) *SandboxPolicy {
+	t := baseType(reflect.TypeOf(sample))
+	if p.AllowedMethods[t] == nil {
+		p.AllowedMethods[t] = map[string]bool{}
+	}
+	for _, name := range names {
+		p.AllowedMethods[t][name] = true
+	}
+	return p
+}
+
+// DenyFilters denies the given filters
+func (p *SandboxPolicy) DenyFilters(names ...string) *SandboxPolicy {
+	for _, name := range names {
+		p.DeniedFilters[name] = true
+	}
+	return p
+}
+
+// DenyStatements denies the given statements
+func (p *SandboxPolicy) DenyStatements(names ...string) *SandboxPolicy {
+	for _, name := range names {
+		p.DeniedStatements[name] = true
+	}
+	return p
+}
+
+// IsSafeType implements SecurityPolicy
+func (p *SandboxPolicy) IsSafeType(t reflect.Type) bool {
+	return !p.DeniedTypes[baseType(t)]
+}
+
+// IsSafeField implements SecurityPolicy
+func (p *SandboxPolicy) IsSafeField(t reflect.Type, field reflect.StructField) bool {
+	if field.PkgPath != "" {
+		// Unexported field
+		return false
+	}
+	return !p.DeniedFields[baseType(t)][field.Name]
+}
+
+// IsSafeMethod implements SecurityPolicy
+func (p *SandboxPolicy) IsSafeMethod(t reflect.Type, method reflect.Method) bool {
+	t = baseType(t)
+	if strings.HasPrefix(t.PkgPath(), "github.com/noirbizarre/gonja/") {
+		// gonja's own types (loop, caller...) are trusted
+		return true
+	}
+	return p.AllowedMethods[t][method.Name]
+}
+
+// IsSafeFilter implements SecurityPolicy
+func (p *SandboxPolicy) IsSafeFilter(name string) bool {
+	return !p.DeniedFilters[name]
+}
+
+// IsSafeStatement implements SecurityPolicy
+func (p *SandboxPolicy) IsSafeStatement(name string) bool {
+	return !p.DeniedStatements[name]
+}
@@ -54,6 +54,19 @@ type Statement interface {
 	Execute(*Renderer, *nodes.StatementBlock) error
 }
 
+// ErrBreak and ErrContinue are returned by the loop control statements
+// ({% break %} and {% continue %}) to interrupt the rendering up to the enclosing loop.
+var (
+	ErrBreak    = errors.New("break")
+	ErrContinue = errors.New("continue")
+)
+
+// IsLoopControl returns true if err is (or has been caused by) a loop control interruption
+func IsLoopControl(err error) bool {
+	cause := errors.Cause(err)
+	return cause == ErrBreak || cause == ErrContinue
+}
+
 type StatementSet map[string]parser.StatementParser
 
 // Exists returns true if the given test is already registered
@@ -2,6 +2,7 @@ package exec
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"strings"
 
@@ -16,6 +17,18 @@ type TemplateLoader interface {
 	GetTemplate(string) (*Template, error)
 }
 
+// TemplateVersioner is implemented by the TemplateLoaders
+// able to report the current version of a template.
+type TemplateVersioner interface {
+	TemplateVersion(name string) string
+}
+
+// TemplateResolver is implemented by the TemplateLoaders
+// able to resolve a template name relative to the template loading it.
+type TemplateResolver interface {
+	ResolveTemplate(parent, name string) string
+}
+
 type Template struct {
 	Name   string
 	Reader io.Reader
@@ -29,6 +42,13 @@ type Template struct {
 
 	Root   *nodes.Template
 	Macros MacroSet
+
+	// The templates loaded while parsing (extends, include and import), recursively
+	Dependencies []string
+	// The versions of this template and its dependencies before being read, if tracked
+	Versions map[string]string
+	// The sources of the dependencies, to report the errors raised while rendering them
+	Sources map[string]string
 }
 
 func NewTemplate(name string, source string, cfg *EvalConfig) (*Template, error) {
@@ -37,72 +57,199 @@ func NewTemplate(name string, source string, cfg *EvalConfig) (*Template, error)
 		Env:    cfg,
 		Name:   name,
 		Source: source,
-		Tokens: tokens.Lex(source),
+		Tokens: tokens.LexWithConfig(source, cfg.Config),
 	}
 
 	// Parse it
 	t.Parser = parser.NewParser(name, cfg.Config, t.Tokens)
 	t.Parser.Statements = *t.Env.Statements
-	t.Parser.TemplateParser = t.Env.GetTemplate
+	t.Parser.TemplateParser = t.loadDependency
+	t.Parser.TemplateResolver = t.Env.ResolveTemplate
 	root, err := t.Parser.Parse()
 	if err != nil {
-		return nil, err
+		return nil, parseError(name, source, t.Tokens, err)
 	}
+	root.Autoescape = cfg.SelectAutoescape(name)
 	t.Root = root
 
 	return t, nil
 }
 
-func (tpl *Template) execute(ctx map[string]interface{}, out io.StringWriter) error {
+// loadDependency loads a template required while parsing
+// and records it along with its own dependencies.
+// Missing templates are recorded too as they may be created later.
+func (tpl *Template) loadDependency(name string) (*nodes.Template, error) {
+	tpl.addDependency(name)
+	// Get the version first so a change while loading is detected
+	if versioner, ok := tpl.Env.Loader.(TemplateVersioner); ok && tpl.Env.Config.AutoReload {
+		tpl.addVersion(name, versioner.TemplateVersion(name))
+	}
+	dep, err := tpl.Env.Loader.GetTemplate(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, `Unable to parse template "%s"`, name)
+	}
+	for _, sub := range dep.Dependencies {
+		tpl.addDependency(sub)
+	}
+	for sub, version := range dep.Versions {
+		tpl.addVersion(sub, version)
+	}
+	tpl.addSource(name, dep.Source)
+	for sub, source := range dep.Sources {
+		tpl.addSource(sub, source)
+	}
+	return dep.Root, nil
+}
+
+// addSource records the source of a dependency
+func (tpl *Template) addSource(name, source string) {
+	if name == tpl.Name {
+		return
+	}
+	if tpl.Sources == nil {
+		tpl.Sources = map[string]string{}
+	}
+	tpl.Sources[name] = source
+}
+
+// addVersion records the version of a dependency,
+// keeping the first one recorded.
+func (tpl *Template) addVersion(name, version string) {
+	if name == tpl.Name {
+		return
+	}
+	if tpl.Versions == nil {
+		tpl.Versions = map[string]string{}
+	}
+	if _, ok := tpl.Versions[name]; !ok {
+		tpl.Versions[name] = version
+	}
+}
+
+func (tpl *Template) addDependency(name string) {
+	if name == tpl.Name {
+		return
+	}
+	for _, dep := range tpl.Dependencies {
+		if dep == name {
+			return
+		}
+	}
+	tpl.Dependencies = append(tpl.Dependencies, name)
+}
+
+// Lint parses source in recovery mode and returns all its syntax errors
+// or nil if the template is valid.
+func Lint(name string, source string, cfg *EvalConfig) []*TemplateError {
+	p := parser.NewParser(name, cfg.Config, tokens.LexRecover(source, cfg.Config))
+	p.Statements = *cfg.Statements
+	p.TemplateParser = cfg.GetTemplate
+	p.TemplateResolver = cfg.ResolveTemplate
+	p.Recover = true
+
+	_, err := p.Parse()
+	if err == nil {
+		return nil
+	}
+	list, ok := err.(parser.ErrorList)
+	if !ok {
+		list = parser.ErrorList{err}
+	}
+	errs := make([]*TemplateError, len(list))
+	for idx, e := range list {
+		errs[idx] = parseError(name, source, nil, e)
+	}
+	return errs
+}
+
+func (tpl *Template) execute(stdCtx context.Context, ctx map[string]interface{}, out io.Writer) error {
 	exCtx := tpl.Env.Globals.Inherit()
 	exCtx.Update(ctx)
 
-	var builder strings.Builder
-	renderer := NewRenderer(exCtx, &builder, tpl.Env, tpl)
+	// Ensure anything bound to the rendering is released at the end
+	var renderCtx context.Context
+	var cancel context.CancelFunc
+	if timeout := tpl.Env.Limits.Timeout; timeout > 0 {
+		renderCtx, cancel = context.WithTimeout(stdCtx, timeout)
+	} else {
+		renderCtx, cancel = context.WithCancel(stdCtx)
+	}
+	defer cancel()
+
+	budget := &outputBudget{limit: tpl.Env.Limits.MaxOutputBytes}
+	writer := newOutputWriter(out, tpl.Env.Config.KeepTrailingNewline, budget)
+	renderer := NewRenderer(exCtx, writer, tpl.Env, tpl)
+	renderer.stdCtx = renderCtx
+	renderer.state.budget = budget
 
 	err := renderer.Execute()
 	if err != nil {
+		if renderCtx.Err() == context.DeadlineExceeded && stdCtx.Err() == nil {
+			err = &TimeLimitError{Limit: tpl.Env.Limits.Timeout}
+		}
+		if te, ok := AsTemplateError(err); ok {
+			if te.Source == "" {
+				te.Source = tpl.sourceOf(te.Name)
+			}
+			return te
+		}
 		return errors.Wrap(err, `Unable to Execute template`)
 	}
-	out.WriteString(renderer.String())
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, `Unable to write template output`)
+	}
 
 	return nil
 }
 
+// sourceOf returns the source of the named template (this one or a dependency)
+// or an empty string if it is not available
+func (tpl *Template) sourceOf(name string) string {
+	if name == tpl.Name {
+		return tpl.Source
+	}
+	return tpl.Sources[name]
+}
+
 func (tpl *Template) newBufferAndExecute(ctx map[string]interface{}) (*bytes.Buffer, error) {
 	var buffer bytes.Buffer
 	// Create output buffer
 	// We assume that the rendered template will be 30% larger
 	// buffer := bytes.NewBuffer(make([]byte, 0, int(float64(tpl.size)*1.3)))
-	if err := tpl.execute(ctx, &buffer); err != nil {
+	if err := tpl.execute(context.Background(), ctx, &buffer); err != nil {
 		return nil, err
 	}
 	return &buffer, nil
 }
 
-// // Executes the template with the given context and writes to writer (io.Writer)
-// // on success. Context can be nil. Nothing is written on error; instead the error
-// // is being returned.
-// func (tpl *Template) ExecuteWriter(ctx *Context, writer io.Writer) error {
-// 	buf, err := tpl.newBufferAndExecute(ctx)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	_, err = buf.WriteTo(writer)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	return nil
-// }
-
-// // // Same as ExecuteWriter. The only difference between both functions is that
-// // // this function might already have written parts of the generated template in the
-// // // case of an execution error because there's no intermediate buffer involved for
-// // // performance reasons. This is handy if you need high performance template
-// // // generation or if you want to manage your own pool of buffers.
-// // func (tpl *Template) ExecuteWriterUnbuffered(ctx *Context, writer io.Writer) error {
-// // 	return tpl.newTemplateWriterAndExecute(ctx, writer)
-// // }
+// Executes the template with the given context and streams the output to writer
+// as it is rendered. Context can be nil. Parts of the output may already have been
+// written in the case of an execution error, use ExecuteWriterBuffered to avoid it.
+func (tpl *Template) ExecuteWriter(ctx map[string]interface{}, writer io.Writer) error {
+	return tpl.execute(context.Background(), ctx, writer)
+}
+
+// Executes the template with the given context and streams the output to writer
+// like ExecuteWriter. The rendering is interrupted as soon as stdCtx is done
+// and stdCtx is made available to filters and functions through the Evaluator.
+func (tpl *Template) ExecuteContext(stdCtx context.Context, ctx map[string]interface{}, writer io.Writer) error {
+	return tpl.execute(stdCtx, ctx, writer)
+}
+
+// Executes the template with the given context and writes to writer
+// on success. Context can be nil. Nothing is written on error; instead the error
+// is being returned.
+func (tpl *Template) ExecuteWriterBuffered(ctx map[string]interface{}, writer io.Writer) error {
+	buf, err := tpl.newBufferAndExecute(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(writer)
+	if err != nil {
+		return errors.Wrap(err, `Unable to write template output`)
+	}
+	return nil
+}
 
 // Executes the template and returns the rendered template as a []byte
 func (tpl *Template) ExecuteBytes(ctx map[string]interface{}) ([]byte, error) {
@@ -116,7 +263,7 @@ func (tpl *Template) ExecuteBytes(ctx map[string]interface{}) ([]byte, error) {
 // Executes the template and returns the rendered template as a string
 func (tpl *Template) Execute(ctx map[string]interface{}) (string, error) {
 	var b strings.Builder
-	err := tpl.execute(ctx, &b)
+	err := tpl.execute(context.Background(), ctx, &b)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,296 @@
+package exec
+
+import (
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/noirbizarre/gonja/nodes"
+	"github.com/noirbizarre/gonja/tokens"
+)
+
+// Undefined describes a missing variable, attribute or item
+type Undefined struct {
+	Name  string        // The undefined expression, ie. "user.name"
+	Token *tokens.Token // Where it has been accessed, if known
+}
+
+// Err returns the UndefinedError raised when u can't be used
+func (u *Undefined) Err() error {
+	return &UndefinedError{Name: u.Name, Token: u.Token}
+}
+
+// UndefinedValue wraps an Undefined into a Value.
+// It behaves like a nil value unless the Undefined policy decides otherwise.
+func UndefinedValue(u *Undefined) *Value {
+	return &Value{undefined: u}
+}
+
+// UndefinedError is raised when an undefined value is used
+// in a way the Undefined policy does not allow.
+type UndefinedError struct {
+	Name  string
+	Token *tokens.Token // The position in the template, if known
+}
+
+func (e *UndefinedError) Error() string {
+	if e.Token == nil {
+		return fmt.Sprintf(`'%s' is undefined`, e.Name)
+	}
+	return fmt.Sprintf(`'%s' is undefined (Line: %d Col: %d)`, e.Name, e.Token.Line, e.Token.Col)
+}
+
+// Position returns the position in the template, if known
+func (e *UndefinedError) Position() *tokens.Token {
+	return e.Token
+}
+
+// IsUndefinedError returns true if err is (or has been caused by) an UndefinedError
+func IsUndefinedError(err error) bool {
+	for err != nil {
+		if _, ok := err.(*UndefinedError); ok {
+			return true
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = causer.Cause()
+	}
+	return false
+}
+
+// UndefinedPolicy decides what happens when an undefined value is used
+type UndefinedPolicy interface {
+	// Print returns the text rendered for u
+	Print(u *Undefined) (string, error)
+	// Iterate returns an error if u can't be iterated over.
+	// It is iterated as an empty sequence otherwise.
+	Iterate(u *Undefined) error
+	// Getattr returns the attribute or item name of u
+	Getattr(u *Undefined, name string) (*Value, error)
+	// Operate returns an error if u can't be used as an operand of op:
+	// a comparison, an arithmetic operator, "bool" for its truth value
+	// or "filter" when passed to any filter but "default".
+	Operate(u *Undefined, op string) error
+}
+
+// DefaultUndefined behaves like a nil value: it renders as an empty string,
+// iterates as an empty sequence and can be used in any expression.
+// Only its attributes and items raise an UndefinedError.
+type DefaultUndefined struct{}
+
+// Print implements UndefinedPolicy
+func (DefaultUndefined) Print(u *Undefined) (string, error) { return "", nil }
+
+// Iterate implements UndefinedPolicy
+func (DefaultUndefined) Iterate(u *Undefined) error { return nil }
+
+// Getattr implements UndefinedPolicy
+func (DefaultUndefined) Getattr(u *Undefined, name string) (*Value, error) {
+	return nil, u.Err()
+}
+
+// Operate implements UndefinedPolicy
+func (DefaultUndefined) Operate(u *Undefined, op string) error { return nil }
+
+// StrictUndefined raises an UndefinedError on any use
+// but the "defined" and "undefined" tests and the "default" filter.
+type StrictUndefined struct{}
+
+// Print implements UndefinedPolicy
+func (StrictUndefined) Print(u *Undefined) (string, error) { return "", u.Err() }
+
+// Iterate implements UndefinedPolicy
+func (StrictUndefined) Iterate(u *Undefined) error { return u.Err() }
+
+// Getattr implements UndefinedPolicy
+func (StrictUndefined) Getattr(u *Undefined, name string) (*Value, error) {
+	return nil, u.Err()
+}
+
+// Operate implements UndefinedPolicy
+func (StrictUndefined) Operate(u *Undefined, op string) error { return u.Err() }
+
+// ChainableUndefined renders as an empty string, iterates as an empty sequence,
+// is false and equal to other undefined values.
+// Its attributes and items are undefined too instead of raising an error,
+// any other use (ie. arithmetic or ordering) raises an UndefinedError.
+type ChainableUndefined struct {
+	DefaultUndefined
+}
+
+// Getattr implements UndefinedPolicy
+func (ChainableUndefined) Getattr(u *Undefined, name string) (*Value, error) {
+	return UndefinedValue(&Undefined{Name: u.Name + "." + name, Token: u.Token}), nil
+}
+
+// Operate implements UndefinedPolicy
+func (ChainableUndefined) Operate(u *Undefined, op string) error {
+	switch op {
+	case "bool", "not", "and", "or", "==", "!=", "<>", "in", "not in", "~", "filter":
+		return nil
+	}
+	return u.Err()
+}
+
+// DebugUndefined behaves like DefaultUndefined
+// but renders as the undefined expression (ie. "{{ user.name }}").
+type DebugUndefined struct {
+	DefaultUndefined
+}
+
+// Print implements UndefinedPolicy
+func (DebugUndefined) Print(u *Undefined) (string, error) {
+	return fmt.Sprintf("{{ %s }}", u.Name), nil
+}
+
+// LoggingUndefined logs any use of an undefined value
+// before delegating its behavior to another policy.
+type LoggingUndefined struct {
+	Policy UndefinedPolicy
+	Logger log.FieldLogger
+}
+
+// NewLoggingUndefined creates a LoggingUndefined delegating to policy.
+// DefaultUndefined and the logrus standard logger are used if nil.
+func NewLoggingUndefined(policy UndefinedPolicy, logger log.FieldLogger) *LoggingUndefined {
+	if policy == nil {
+		policy = DefaultUndefined{}
+	}
+	if logger == nil {
+		logger = log.StandardLogger()
+	}
+	return &LoggingUndefined{Policy: policy, Logger: logger}
+}
+
+func (l *LoggingUndefined) log(u *Undefined, err error) {
+	entry := l.Logger.WithField("name", u.Name)
+	if u.Token != nil {
+		entry = entry.WithField("line", u.Token.Line).WithField("col", u.Token.Col)
+	}
+	if err != nil {
+		entry.Errorf("Template variable error: %s", err)
+	} else {
+		entry.Warnf("Template variable warning: '%s' is undefined", u.Name)
+	}
+}
+
+// Print implements UndefinedPolicy
+func (l *LoggingUndefined) Print(u *Undefined) (string, error) {
+	out, err := l.Policy.Print(u)
+	l.log(u, err)
+	return out, err
+}
+
+// Iterate implements UndefinedPolicy
+func (l *LoggingUndefined) Iterate(u *Undefined) error {
+	err := l.Policy.Iterate(u)
+	l.log(u, err)
+	return err
+}
+
+// Getattr implements UndefinedPolicy
+func (l *LoggingUndefined) Getattr(u *Undefined, name string) (*Value, error) {
+	value, err := l.Policy.Getattr(u, name)
+	l.log(u, err)
+	return value, err
+}
+
+// Operate implements UndefinedPolicy
+func (l *LoggingUndefined) Operate(u *Undefined, op string) error {
+	err := l.Policy.Operate(u, op)
+	l.log(u, err)
+	return err
+}
+
+// undefinedPolicy returns the configured UndefinedPolicy or the default one
+func (e *Evaluator) undefinedPolicy() UndefinedPolicy {
+	if e.Undefined == nil {
+		return DefaultUndefined{}
+	}
+	return e.Undefined
+}
+
+// undefined returns an undefined value for the expression node
+func (e *Evaluator) undefined(node nodes.Node) *Value {
+	return UndefinedValue(&Undefined{Name: expressionName(node), Token: node.Position()})
+}
+
+// undefinedAttribute returns the attribute or item of an undefined value,
+// as decided by the Undefined policy
+func (e *Evaluator) undefinedAttribute(value *Value, node nodes.Node, name string, index int) *Value {
+	if name == "" {
+		name = strconv.Itoa(index)
+	}
+	attr, err := e.undefinedPolicy().Getattr(value.undefined, name)
+	if err != nil {
+		return AsValue(err)
+	}
+	if attr.IsUndefined() {
+		// Report the position of the last access
+		attr.undefined.Token = node.Position()
+	}
+	return attr
+}
+
+// IsTrue returns the truth value of value.
+// The Undefined policy decides for undefined values.
+func (e *Evaluator) IsTrue(value *Value) (bool, error) {
+	if value.undefined != nil {
+		if err := e.undefinedPolicy().Operate(value.undefined, "bool"); err != nil {
+			return false, err
+		}
+	}
+	return value.IsTrue(), nil
+}
+
+// CheckIterate returns an error if value is undefined
+// and the Undefined policy does not allow iterating over it.
+func (e *Evaluator) CheckIterate(value *Value) error {
+	if value.undefined == nil {
+		return nil
+	}
+	return e.undefinedPolicy().Iterate(value.undefined)
+}
+
+// checkOperand returns an error if value is undefined
+// and the Undefined policy does not allow using it as an operand of op.
+func (e *Evaluator) checkOperand(value *Value, op string) error {
+	if value == nil || value.undefined == nil {
+		return nil
+	}
+	return e.undefinedPolicy().Operate(value.undefined, op)
+}
+
+// expressionName returns the template representation of a variable expression
+func expressionName(node nodes.Node) string {
+	switch n := node.(type) {
+	case *nodes.Name:
+		return n.Name.Val
+	case *nodes.Getattr:
+		if n.Attr != "" {
+			return expressionName(n.Node) + "." + n.Attr
+		}
+		return expressionName(n.Node) + "." + strconv.Itoa(n.Index)
+	case *nodes.Getitem:
+		if n.Key != nil {
+			return fmt.Sprintf("%s[%s]", expressionName(n.Node), expressionName(n.Key))
+		}
+		if n.Arg != "" {
+			return fmt.Sprintf("%s['%s']", expressionName(n.Node), n.Arg)
+		}
+		return fmt.Sprintf("%s[%d]", expressionName(n.Node), n.Index)
+	case *nodes.Call:
+		return expressionName(n.Func) + "()"
+	case *nodes.String:
+		return fmt.Sprintf("'%s'", n.Val)
+	case *nodes.InlineIfExpression:
+		return fmt.Sprintf("%s if %s", expressionName(n.True), expressionName(n.Condition))
+	case *nodes.BinaryExpression:
+		return fmt.Sprintf("%s %s %s", expressionName(n.Left), n.Operator.Token.Val, expressionName(n.Right))
+	default:
+		return node.String()
+	}
+}
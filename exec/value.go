@@ -17,6 +17,8 @@ import (
 type Value struct {
 	Val  reflect.Value
 	Safe bool // used to indicate whether a Value needs explicit escaping in the template
+
+	undefined *Undefined // set if the value is undefined
 }
 
 // AsValue converts any given Value to a gonja.Value
@@ -99,6 +101,12 @@ func (v *Value) IsIterable() bool {
 	return v.IsString() || v.IsList() || v.IsDict()
 }
 
+// IsUndefined checks whether the value is undefined
+// (ie. a missing variable, attribute or item)
+func (v *Value) IsUndefined() bool {
+	return v.undefined != nil
+}
+
 // IsNil checks whether the underlying value is NIL
 func (v *Value) IsNil() bool {
 	return !v.getResolvedValue().IsValid()
@@ -119,6 +127,14 @@ func (v *Value) Error() string {
 	return ""
 }
 
+// Cause returns the wrapped error, allowing errors.Cause to go through values
+func (v *Value) Cause() error {
+	if v.IsError() {
+		return v.Interface().(error)
+	}
+	return nil
+}
+
 // String returns a string for the underlying value. If this value is not
 // of type string, gonja tries to convert it. Currently the following
 // types for underlying values are supported:
@@ -807,7 +823,14 @@ func (v *Value) Getitem(key interface{}) (*Value, bool) {
 
 	case int:
 		switch val.Kind() {
-		case reflect.String, reflect.Array, reflect.Slice:
+		case reflect.String:
+			// Index characters, not bytes
+			runes := []rune(val.String())
+			if t >= 0 && len(runes) > t {
+				return AsValue(string(runes[t])), true
+			}
+			return AsValue(nil), false
+		case reflect.Array, reflect.Slice:
 			if t >= 0 && val.Len() > t {
 				atIndex := val.Index(t)
 				if atIndex.IsValid() {
@@ -848,16 +871,36 @@ func (v *Value) Set(key string, value interface{}) error {
 		}
 	}
 
+	if val.Type() == TypeDict && val.CanAddr() {
+		item, ok := value.(*Value)
+		if !ok {
+			item = AsValue(value)
+		}
+		val.Addr().Interface().(*Dict).Set(AsValue(key), item)
+		return nil
+	}
+
 	switch val.Kind() {
 	case reflect.Struct:
 		field := val.FieldByName(key)
-		if field.IsValid() && field.CanSet() {
-			field.Set(reflect.ValueOf(value))
-		} else {
+		if !field.IsValid() || !field.CanSet() {
 			return errors.Errorf(`Can't write field "%s"`, key)
 		}
+		rv, err := assignableTo(value, field.Type())
+		if err != nil {
+			return errors.Wrapf(err, `Can't write field "%s"`, key)
+		}
+		field.Set(rv)
 	case reflect.Map:
-		val.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(value))
+		rk, err := assignableTo(key, val.Type().Key())
+		if err != nil {
+			return errors.Wrapf(err, `Can't use "%s" as key`, key)
+		}
+		rv, err := assignableTo(value, val.Type().Elem())
+		if err != nil {
+			return errors.Wrapf(err, `Can't set item "%s"`, key)
+		}
+		val.SetMapIndex(rk, rv)
 	default:
 		return errors.Errorf(`Unkown type "%s", can't set value on "%s"`, val.Kind(), key)
 	}
@@ -865,6 +908,28 @@ func (v *Value) Set(key string, value interface{}) error {
 	return nil
 }
 
+// assignableTo converts value into a reflect.Value assignable to typ
+func assignableTo(value interface{}, typ reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(typ), nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(typ) {
+		return rv, nil
+	}
+	if v, ok := value.(*Value); ok {
+		return assignableTo(v.Interface(), typ)
+	}
+	if isNumeric(rv.Kind()) && isNumeric(typ.Kind()) {
+		return rv.Convert(typ), nil
+	}
+	return rv, errors.Errorf(`%s is not assignable to %s`, rv.Type(), typ)
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
 type ValuesList []*Value
 
 func (vl ValuesList) Len() int {
@@ -969,6 +1034,17 @@ func (d *Dict) Get(key *Value) *Value {
 	return AsValue(nil)
 }
 
+// Set sets the value of key, replacing the existing one if any
+func (d *Dict) Set(key *Value, value *Value) {
+	for _, pair := range d.Pairs {
+		if pair.Key.EqualValueTo(key) {
+			pair.Value = value
+			return
+		}
+	}
+	d.Pairs = append(d.Pairs, &Pair{Key: key, Value: value})
+}
+
 var TypeDict = reflect.TypeOf(Dict{})
 
 type sortRunes []rune
@@ -0,0 +1,62 @@
+package exec
+
+import (
+	"io"
+)
+
+// outputWriter streams the rendered output to the final writer.
+// It holds back a trailing newline until more output is written
+// so it can be dropped at the end of the rendering if required,
+// and retains the first write error.
+type outputWriter struct {
+	writer              io.Writer
+	keepTrailingNewline bool
+	budget              *outputBudget
+	pending             string
+	err                 error
+}
+
+func newOutputWriter(w io.Writer, keepTrailingNewline bool, budget *outputBudget) *outputWriter {
+	return &outputWriter{
+		writer:              w,
+		keepTrailingNewline: keepTrailingNewline,
+		budget:              budget,
+	}
+}
+
+// WriteString implements io.StringWriter
+func (w *outputWriter) WriteString(s string) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(s) == 0 {
+		return 0, nil
+	}
+	txt := w.pending + s
+	w.pending = ""
+	if !w.keepTrailingNewline {
+		idx := len(txt) - trailingNewline(txt)
+		txt, w.pending = txt[:idx], txt[idx:]
+	}
+	if w.budget != nil {
+		if err := w.budget.spend(len(txt)); err != nil {
+			w.err = err
+			return 0, err
+		}
+	}
+	if _, err := io.WriteString(w.writer, txt); err != nil {
+		w.err = err
+		return 0, err
+	}
+	return len(s), nil
+}
+
+// Close writes the pending newline if it has to be kept
+// and returns the first error encountered while writing.
+func (w *outputWriter) Close() error {
+	if w.err == nil && w.keepTrailingNewline && len(w.pending) > 0 {
+		_, w.err = io.WriteString(w.writer, w.pending)
+	}
+	w.pending = ""
+	return w.err
+}
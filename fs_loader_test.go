@@ -0,0 +1,110 @@
+package gonja_test
+
+import (
+	"embed"
+	"io/ioutil"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+//go:embed testData/autoescape
+var embedded embed.FS
+
+var fsLoaderFiles = fstest.MapFS{
+	"templates/layouts/base.html": {Data: []byte(
+		`<main>{% block content %}{% endblock %}</main>`)},
+	"templates/pages/index.html": {Data: []byte(
+		`{% extends "../layouts/base.html" %}{% block content %}` +
+			`{% from "../macros.html" import hello %}{{ hello(name) }}|{% include "./partials/item.html" %}|{% include partial %}{% endblock %}`)},
+	"templates/pages/partials/item.html":  {Data: []byte(`item`)},
+	"templates/pages/partials/other.html": {Data: []byte(`other`)},
+	"templates/macros.html":               {Data: []byte(`{% macro hello(name) %}Hello {{ name }}{% endmacro %}`)},
+	"templates/notes.txt":                 {Data: []byte(`notes`)},
+	"secret.txt":                          {Data: []byte(`secret`)},
+}
+
+func TestFSLoader(t *testing.T) {
+	assert := assert.New(t)
+	loader, err := loaders.NewFSLoader(fsLoaderFiles, "templates")
+	if !assert.Nil(err) {
+		return
+	}
+	ctx := map[string]interface{}{"name": "john", "partial": "./partials/other.html"}
+	out, err := tu.RenderFile(t, tu.NewEnv(loader), "pages/index.html", ctx)
+	assert.Nil(err)
+	assert.Equal("<main>Hello john|item|other</main>", out)
+}
+
+func TestFSLoaderGet(t *testing.T) {
+	loader := loaders.MustNewFSLoader(fsLoaderFiles, "templates")
+	for _, name := range []string{"notes.txt", "/notes.txt", "pages/../notes.txt"} {
+		reader, err := loader.Get(name)
+		if assert.Nil(t, err, name) {
+			content, _ := ioutil.ReadAll(reader)
+			assert.Equal(t, "notes", string(content), name)
+		}
+	}
+	for _, name := range []string{"../secret.txt", "missing.html", "pages"} {
+		_, err := loader.Get(name)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestFSLoaderResolve(t *testing.T) {
+	loader := loaders.MustNewFSLoader(fsLoaderFiles, "")
+	cases := []struct {
+		parent   string
+		name     string
+		expected string
+	}{
+		{"pages/index.html", "layouts/base.html", "layouts/base.html"},
+		{"pages/index.html", "./item.html", "pages/item.html"},
+		{"pages/index.html", "../macros.html", "macros.html"},
+		{"index.html", "./item.html", "item.html"},
+		{"string", "./item.html", "item.html"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, loader.Resolve(c.parent, c.name), "%s from %s", c.name, c.parent)
+	}
+}
+
+func TestFSLoaderListTemplates(t *testing.T) {
+	assert := assert.New(t)
+	loader := loaders.MustNewFSLoader(fsLoaderFiles, "templates")
+
+	names, err := loader.ListTemplates()
+	assert.Nil(err)
+	assert.Equal([]string{
+		"layouts/base.html",
+		"macros.html",
+		"notes.txt",
+		"pages/index.html",
+		"pages/partials/item.html",
+		"pages/partials/other.html",
+	}, names)
+
+	names, err = loader.ListTemplates(".txt", "xml")
+	assert.Nil(err)
+	assert.Equal([]string{"notes.txt"}, names)
+}
+
+func TestFSLoaderInvalidDirectory(t *testing.T) {
+	_, err := loaders.NewFSLoader(fsLoaderFiles, "missing")
+	assert.NotNil(t, err)
+	_, err = loaders.NewFSLoader(fsLoaderFiles, "templates/notes.txt")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "is not a directory")
+	}
+}
+
+func TestFSLoaderEmbed(t *testing.T) {
+	env := tu.NewEnv(loaders.MustNewFSLoader(embedded, "testData/autoescape"))
+	out, err := tu.RenderFile(t, env, "notes.txt", map[string]interface{}{"value": "embedded"})
+	assert.Nil(t, err)
+	assert.Equal(t, "embedded", out)
+}
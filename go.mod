@@ -1,6 +1,6 @@
 module github.com/noirbizarre/gonja
 
-go 1.14
+go 1.16
 
 require (
 	github.com/bmuller/arrow v0.0.0-20180318014521-b14bfde8dff2
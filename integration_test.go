@@ -3,6 +3,7 @@
 package gonja_test
 
 import (
+	"fmt"
 	"testing"
 
 	tu "github.com/noirbizarre/gonja/testutils"
@@ -46,6 +47,40 @@ func TestStatements(t *testing.T) {
 	tu.GlobTemplateTests(t, root, env)
 }
 
+func TestNewlines(t *testing.T) {
+	root := "./testData/newlines"
+	env := tu.TestEnv(root)
+	env.NewlineSequence = "\r\n"
+	env.TrimBlocks = true
+	env.LstripBlocks = true
+	tu.GlobTemplateTests(t, root, env)
+}
+
+func TestDelimiters(t *testing.T) {
+	root := "./testData/delimiters"
+	env := tu.TestEnv(root)
+	env.BlockStartString = `\BLOCK{`
+	env.BlockEndString = "}"
+	env.VariableStartString = `\VAR{`
+	env.VariableEndString = "}"
+	env.CommentStartString = `\#{`
+	env.CommentEndString = "}"
+	tu.GlobTemplateTests(t, root, env)
+}
+
+func TestLineStatements(t *testing.T) {
+	root := "./testData/linestatements"
+	for _, trimBlocks := range []bool{false, true} {
+		env := tu.TestEnv(root)
+		env.LineStatementPrefix = "#"
+		env.LineCommentPrefix = "##"
+		env.TrimBlocks = trimBlocks
+		t.Run(fmt.Sprintf("TrimBlocks=%t", trimBlocks), func(t *testing.T) {
+			tu.GlobTemplateTests(t, root, env)
+		})
+	}
+}
+
 // func TestCompilationErrors(t *testing.T) {
 // 	tu.GlobErrorTests(t, "./testData/errors/compilation")
 // }
@@ -0,0 +1,182 @@
+package gonja_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+func TestOutputLimit(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxOutputBytes: 10}
+
+	out, err := tu.Render(t, env, "{% for i in range(5) %}{{ i }}{% endfor %}", nil)
+	assert.Nil(err)
+	assert.Equal("01234", out)
+
+	out, err = tu.Render(t, env, "{% for i in range(100) %}{{ i }}{% if true %}{% endif %}{% endfor %}", nil)
+	if assert.NotNil(err) {
+		assert.IsType(&exec.OutputLimitError{}, errors.Cause(err))
+	}
+	assert.True(len(out) <= 10, "output should not exceed the limit")
+}
+
+func TestOutputLimitIgnoresStrippedNewline(t *testing.T) {
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxOutputBytes: 5}
+	_, err := tu.Render(t, env, "12345\n", nil)
+	assert.Nil(t, err)
+}
+
+func TestOutputLimitOnBuffers(t *testing.T) {
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxOutputBytes: 10}
+	for _, source := range []string{
+		`{% macro big() %}{% for i in range(100) %}{{ i }}{% endfor %}{% endmacro %}{% set out = big() %}`,
+		`{% set out %}{% for i in range(100) %}{{ i }}{% endfor %}{% endset %}`,
+		`{% filter length %}{% for i in range(100) %}{{ i }}{% endfor %}{% endfilter %}`,
+	} {
+		out, err := tu.Render(t, env, source, nil)
+		if assert.NotNil(t, err, source) {
+			assert.IsType(t, &exec.OutputLimitError{}, errors.Cause(err), source)
+		}
+		assert.Equal(t, "", out, source)
+	}
+
+	// The budget is shared by the buffers and the output
+	out, err := tu.Render(t, env, `{% set out %}12345{% endset %}{{ out }}`, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "12345", out)
+	_, err = tu.Render(t, env, `{% set out %}123456{% endset %}{{ out }}`, nil)
+	if assert.NotNil(t, err) {
+		assert.IsType(t, &exec.OutputLimitError{}, errors.Cause(err))
+	}
+}
+
+func TestLoopLimit(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxLoopIterations: 10}
+
+	_, err := tu.Render(t, env, "{% for i in range(5) %}{% for j in range(2) %}{% endfor %}{% endfor %}", nil)
+	if assert.NotNil(err) {
+		assert.IsType(&exec.LoopLimitError{}, errors.Cause(err))
+	}
+
+	out, err := tu.Render(t, env, "{% for i in range(3) %}{% for j in range(2) %}{{ j }}{% endfor %}{% endfor %}", nil)
+	assert.Nil(err)
+	assert.Equal("010101", out)
+
+	// Filtered out items are not rendered, so they are not counted
+	out, err = tu.Render(t, env, "{% for i in range(100) if i is divisibleby 10 %}{{ i }};{% endfor %}", nil)
+	assert.Nil(err)
+	assert.Equal("0;10;20;30;40;50;60;70;80;90;", out)
+
+	// Neither are the items following a break
+	out, err = tu.Render(t, env, "{% for i in range(100) %}{{ i }}{% if i == 9 %}{% break %}{% endif %}{% endfor %}", nil)
+	assert.Nil(err)
+	assert.Equal("0123456789", out)
+
+	_, err = tu.Render(t, env, "{% for i in range(100) %}{% if i == 10 %}{% break %}{% endif %}{% endfor %}", nil)
+	if assert.NotNil(err) {
+		assert.IsType(&exec.LoopLimitError{}, errors.Cause(err))
+	}
+}
+
+func TestRecursionLimit(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxRecursionDepth: 5}
+	source := `{% macro countdown(n) %}{{ n }}{% if n > 0 %}{{ countdown(n - 1) }}{% endif %}{% endmacro %}{{ countdown(depth) }}`
+
+	out, err := tu.Render(t, env, source, map[string]interface{}{"depth": 4})
+	assert.Nil(err)
+	assert.Equal("43210", out)
+
+	_, err = tu.Render(t, env, source, map[string]interface{}{"depth": 5})
+	if assert.NotNil(err) {
+		assert.IsType(&exec.RecursionLimitError{}, errors.Cause(err))
+		assert.Contains(err.Error(), "Unable to execute macro 'countdown'")
+	}
+}
+
+func TestRecursionLimitOnIncludes(t *testing.T) {
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxRecursionDepth: 3}
+	tpl, err := env.FromFile("testData/limits/recursive_include.tpl")
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = tpl.Execute(map[string]interface{}{"included": "testData/limits/recursive_include.tpl"})
+	if assert.NotNil(t, err) {
+		assert.IsType(t, &exec.RecursionLimitError{}, errors.Cause(err))
+	}
+}
+
+func TestRecursionLimitIgnoresInheritance(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(loaders.NewMapLoader(map[string]string{
+		"base.html":   `<{% block content %}{% endblock %}>`,
+		"child.html":  `{% extends "base.html" %}{% block content %}{% from "macros.html" import hello %}{{ hello() }}{% endblock %}`,
+		"macros.html": `{% macro hello() %}{% include "name.html" %}{% endmacro %}`,
+		"name.html":   `john`,
+	}))
+	env.Limits = exec.Limits{MaxRecursionDepth: 2}
+	tpl, err := env.FromFile("child.html")
+	if !assert.Nil(err) {
+		return
+	}
+	out, err := tpl.Execute(nil)
+	assert.Nil(err)
+	assert.Equal("<john>", out)
+
+	env.Limits = exec.Limits{MaxRecursionDepth: 1}
+	_, err = tpl.Execute(nil)
+	if assert.NotNil(err) {
+		assert.IsType(&exec.RecursionLimitError{}, errors.Cause(err))
+	}
+}
+
+func TestRecursionLimitOnRecursiveLoops(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxRecursionDepth: 3}
+	source := `{% for node in nodes recursive %}{{ loop.depth }}{{ loop(node) }}{% endfor %}`
+
+	out, err := tu.Render(t, env, source, map[string]interface{}{"nodes": [][][][]int{{{{}}}}})
+	assert.Nil(err)
+	assert.Equal("123", out)
+
+	_, err = tu.Render(t, env, source, map[string]interface{}{"nodes": [][][][][]int{{{{{}}}}}})
+	if assert.NotNil(err) {
+		assert.IsType(&exec.RecursionLimitError{}, errors.Cause(err))
+	}
+}
+
+func TestTimeLimit(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{Timeout: 20 * time.Millisecond}
+	ctx := map[string]interface{}{
+		"slow": func() string {
+			time.Sleep(5 * time.Millisecond)
+			return "."
+		},
+	}
+
+	_, err := tu.Render(t, env, "{% for i in range(1000) %}{{ slow() }}{% endfor %}", ctx)
+	if assert.NotNil(err) {
+		assert.IsType(&exec.TimeLimitError{}, errors.Cause(err))
+	}
+
+	out, err := tu.Render(t, env, "{{ slow() }}", ctx)
+	assert.Nil(err)
+	assert.Equal(".", out)
+}
@@ -0,0 +1,28 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/config"
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+func TestLineStatementsInclude(t *testing.T) {
+	assert := assert.New(t)
+	cfg := config.NewConfig()
+	cfg.LineStatementPrefix = "#"
+	cfg.LineCommentPrefix = "##"
+	// Inherited configurations keep the line statements
+	env := gonja.NewEnvironment(cfg.Inherit(), loaders.NewMapLoader(map[string]string{
+		"page.html":    "# include 'partial.html'\nend",
+		"partial.html": "## partial\n# for i in items\n{{ i }}\n# endfor\n",
+	}))
+	out, err := tu.RenderFile(t, env, "page.html", map[string]interface{}{"items": []int{1, 2}})
+	if assert.Nil(err) {
+		assert.Equal("\n1\n2\nend", out)
+	}
+}
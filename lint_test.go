@@ -0,0 +1,81 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja/exec"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+type lintError struct {
+	Phase   exec.Phase
+	Line    int
+	Column  int
+	Message string
+}
+
+var lintCases = []struct {
+	name     string
+	source   string
+	expected []lintError
+}{
+	{"valid", "{% for item in items %}{{ item }}{% endfor %}", nil},
+	{"multiple errors", "{% endfo %}\n{{ 1 + }}\n{% for x in y %}{{ 'abc }}{% endfor %}\n{{ a ! b }}", []lintError{
+		{exec.ParsePhase, 1, 4, "Statement 'endfo' not found (or beginning not provided)"},
+		{exec.ParsePhase, 2, 8, "Expected either a number, string, keyword or identifier."},
+		{exec.LexPhase, 3, 20, "Unterminated string"},
+		{exec.LexPhase, 4, 6, `Unexpected "!"`},
+	}},
+	{"unclosed blocks", "{% if a %}\n{% for x in y %}\n{{ x }}", []lintError{
+		{exec.ParsePhase, 3, 8, "Unexpected EOF, expected tag else or endfor."},
+		{exec.ParsePhase, 3, 8, "Unexpected EOF, expected tag elif or else or endif."},
+	}},
+	{"bad expressions", "{{ (a] }} {{ b }} {{ c( }}", []lintError{
+		{exec.LexPhase, 1, 6, `Unbalanced delimiters, expected ")", got "]"`},
+		{exec.ParsePhase, 1, 25, "Expected either a number, string, keyword or identifier."},
+	}},
+	{"unclosed comment", "{{ a }}{# comment", []lintError{
+		{exec.LexPhase, 1, 10, "unclosed comment"},
+	}},
+	{"failed block statement", "{% if 1 + %}a{% endif %}", []lintError{
+		{exec.ParsePhase, 1, 11, "Expected either a number, string, keyword or identifier."},
+	}},
+	{"failed nested block statement", "{% if a %}{% if 1 + %}b{% endif %}{% endif %}", []lintError{
+		{exec.ParsePhase, 1, 21, "Expected either a number, string, keyword or identifier."},
+	}},
+	{"unclosed raw", "{% raw %}abc", []lintError{
+		{exec.LexPhase, 1, 10, "Unable to find raw closing statement"},
+	}},
+}
+
+func TestLint(t *testing.T) {
+	env := tu.NewEnv(nil)
+	for _, lc := range lintCases {
+		test := lc
+		t.Run(test.name, func(t *testing.T) {
+			var actual []lintError
+			for _, err := range env.LintString(test.source) {
+				actual = append(actual, lintError{err.Phase, err.Line, err.Column, err.Message})
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+
+	errs, err := env.LintFile("testData/template_errors/unclosed.tpl")
+	assert.Nil(err)
+	if assert.Len(errs, 2) {
+		assert.Equal("testData/template_errors/unclosed.tpl", errs[0].Name)
+		assert.Equal("Statement 'endfo' not found (or beginning not provided)", errs[0].Message)
+		assert.Equal("Unexpected EOF, expected tag else or endfor.", errs[1].Message)
+	}
+
+	_, err = env.LintFile("testData/template_errors/missing.tpl")
+	assert.NotNil(err)
+}
@@ -0,0 +1,162 @@
+package loaders
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ChoiceLoader tries a list of loaders in order
+// and loads the template from the first one having it.
+// It allows overriding some templates of another loader (ie. a theme).
+type ChoiceLoader struct {
+	Loaders []Loader
+}
+
+// NewChoiceLoader creates a new ChoiceLoader trying the loaders in order.
+func NewChoiceLoader(loaders ...Loader) *ChoiceLoader {
+	return &ChoiceLoader{Loaders: loaders}
+}
+
+// Get returns the template from the first loader having it.
+// The search stops on the first error not being a missing template.
+func (l *ChoiceLoader) Get(name string) (io.Reader, error) {
+	for _, loader := range l.Loaders {
+		reader, err := loader.Get(name)
+		if err == nil {
+			return reader, nil
+		}
+		if !IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return nil, &NotFoundError{Name: name}
+}
+
+// Version implements Versioner with the version of the template
+// from the first loader having it, so it changes if another loader takes over.
+// The loaders not being Versioner have a constant version.
+func (l *ChoiceLoader) Version(name string) (string, error) {
+	for idx, loader := range l.Loaders {
+		var version string
+		var err error
+		if versioner, ok := loader.(Versioner); ok {
+			version, err = versioner.Version(name)
+		} else {
+			_, err = loader.Get(name)
+		}
+		if err == nil {
+			return fmt.Sprintf("%d:%s", idx, version), nil
+		}
+		if !IsNotFound(err) {
+			return "", err
+		}
+	}
+	return "", &NotFoundError{Name: name}
+}
+
+// Resolve delegates the resolution to the first loader being a Resolver.
+// The name is returned unchanged if there is none.
+func (l *ChoiceLoader) Resolve(parent, name string) string {
+	for _, loader := range l.Loaders {
+		if resolver, ok := loader.(Resolver); ok {
+			return resolver.Resolve(parent, name)
+		}
+	}
+	return name
+}
+
+// ListTemplates implements Lister by merging the templates of all the loaders.
+// An error is returned if any of them is not a Lister.
+func (l *ChoiceLoader) ListTemplates(extensions ...string) ([]string, error) {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, loader := range l.Loaders {
+		list, err := ListTemplates(loader, extensions...)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// PrefixLoader dispatches the templates to a loader depending on their name prefix,
+// ie. "admin/index.html" is loaded as "index.html" by the "admin" loader.
+type PrefixLoader struct {
+	Loaders   map[string]Loader
+	Delimiter string // The prefix delimiter, "/" if empty
+}
+
+// NewPrefixLoader creates a new PrefixLoader from loaders mapped by prefix.
+func NewPrefixLoader(loaders map[string]Loader) *PrefixLoader {
+	return &PrefixLoader{Loaders: loaders, Delimiter: "/"}
+}
+
+// split returns the loader for name along with the name without its prefix
+func (l *PrefixLoader) split(name string) (Loader, string, bool) {
+	delimiter := l.Delimiter
+	if delimiter == "" {
+		delimiter = "/"
+	}
+	parts := strings.SplitN(name, delimiter, 2)
+	if len(parts) != 2 {
+		return nil, "", false
+	}
+	loader, ok := l.Loaders[parts[0]]
+	return loader, parts[1], ok
+}
+
+// Get returns the template from the loader matching its prefix
+// or a NotFoundError if there is none.
+func (l *PrefixLoader) Get(name string) (io.Reader, error) {
+	loader, local, ok := l.split(name)
+	if !ok {
+		return nil, &NotFoundError{Name: name}
+	}
+	return loader.Get(local)
+}
+
+// Version implements Versioner with the version from the loader matching the prefix.
+func (l *PrefixLoader) Version(name string) (string, error) {
+	loader, local, ok := l.split(name)
+	if !ok {
+		return "", &NotFoundError{Name: name}
+	}
+	return Version(loader, local)
+}
+
+// Resolve returns the name of the template loaded as name from the parent template.
+// Names starting with "./" or "../" are relative to the parent template directory,
+// prefix included.
+func (l *PrefixLoader) Resolve(parent, name string) string {
+	return resolveRelative(parent, name)
+}
+
+// ListTemplates implements Lister, the templates are listed with their prefix.
+// An error is returned if any of the loaders is not a Lister.
+func (l *PrefixLoader) ListTemplates(extensions ...string) ([]string, error) {
+	delimiter := l.Delimiter
+	if delimiter == "" {
+		delimiter = "/"
+	}
+	names := []string{}
+	for prefix, loader := range l.Loaders {
+		list, err := ListTemplates(loader, extensions...)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range list {
+			names = append(names, prefix+delimiter+name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -2,11 +2,14 @@ package loaders
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -121,52 +124,237 @@ func (fs *FilesystemLoader) Path(name string) (string, error) {
 	// return filepath.Join(fs.root, name)
 }
 
-// SandboxedFilesystemLoader is still WIP.
+// Version implements Versioner from the file modification time.
+func (fs *FilesystemLoader) Version(name string) (string, error) {
+	realPath, err := fs.Path(name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return "", err
+	}
+	return fileVersion(info), nil
+}
+
+// ListTemplates implements Lister by walking the base directory
+// (or the current working directory if there's none).
+// The names are slash-separated paths relative to this directory.
+func (fs *FilesystemLoader) ListTemplates(extensions ...string) ([]string, error) {
+	root := fs.root
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		root = wd
+	}
+	names := []string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			// Symlinked directories are not followed
+			if info, err = os.Stat(path); err != nil {
+				return nil
+			}
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if hasExtension(name, extensions) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to list templates")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// AccessDeniedError is returned by the SandboxedFilesystemLoader
+// when a template is requested outside of the sandbox.
+type AccessDeniedError struct {
+	Name string // The requested template name
+	Path string // The resolved path
+}
+
+func (e *AccessDeniedError) Error() string {
+	return fmt.Sprintf("Access to template '%s' (%s) is outside of the sandbox", e.Name, e.Path)
+}
+
+// IsAccessDenied returns true if err is (or has been caused by) an AccessDeniedError
+func IsAccessDenied(err error) bool {
+	_, ok := errors.Cause(err).(*AccessDeniedError)
+	return ok
+}
+
+// SandboxedFilesystemLoader is a FilesystemLoader restricting the access
+// to its base directory and to a whitelist of directories or glob patterns.
+// Symlinks are resolved before being checked against the whitelist
+// so they can't be used to escape the sandbox.
 type SandboxedFilesystemLoader struct {
 	*FilesystemLoader
+	allowed []string
 }
 
 // NewSandboxedFilesystemLoader creates a new sandboxed local file system instance.
-func NewSandboxedFilesystemLoader(root string) (*SandboxedFilesystemLoader, error) {
+// The base directory (defaulting to the current working directory) is always allowed.
+// Any additional allowed entry is either a directory, granting access to all its content,
+// or a glob pattern (see https://golang.org/pkg/path/filepath/#Match) matched against
+// the absolute resolved path of the requested templates.
+// Relative entries are relative to the base directory.
+func NewSandboxedFilesystemLoader(root string, allowed ...string) (*SandboxedFilesystemLoader, error) {
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		root = wd
+	}
 	fs, err := NewFileSystemLoader(root)
 	if err != nil {
 		return nil, err
 	}
-	return &SandboxedFilesystemLoader{
+	sandbox := &SandboxedFilesystemLoader{
 		FilesystemLoader: fs,
-	}, nil
-}
-
-// Move sandbox to a virtual fs
-
-/*
-if len(set.SandboxDirectories) > 0 {
-    defer func() {
-        // Remove any ".." or other crap
-        resolvedPath = filepath.Clean(resolvedPath)
-
-        // Make the path absolute
-        absPath, err := filepath.Abs(resolvedPath)
-        if err != nil {
-            panic(err)
-        }
-        resolvedPath = absPath
-
-        // Check against the sandbox directories (once one pattern matches, we're done and can allow it)
-        for _, pattern := range set.SandboxDirectories {
-            matched, err := filepath.Match(pattern, resolvedPath)
-            if err != nil {
-                panic("Wrong sandbox directory match pattern (see http://golang.org/pkg/path/filepath/#Match).")
-            }
-            if matched {
-                // OK!
-                return
-            }
-        }
-
-        // No pattern matched, we have to log+deny the request
-        set.logf("Access attempt outside of the sandbox directories (blocked): '%s'", resolvedPath)
-        resolvedPath = ""
-    }()
-}
-*/
+	}
+	for _, entry := range allowed {
+		if err := sandbox.Allow(entry); err != nil {
+			return nil, err
+		}
+	}
+	return sandbox, nil
+}
+
+// Allow adds a directory or a glob pattern to the sandbox whitelist.
+// Relative entries are relative to the base directory.
+func (fs *SandboxedFilesystemLoader) Allow(entry string) error {
+	abs := entry
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(fs.root, abs)
+	}
+	abs = filepath.Clean(abs)
+	if isPattern(abs) {
+		if _, err := filepath.Match(abs, ""); err != nil {
+			return errors.Wrapf(err, "Invalid sandbox pattern '%s'", entry)
+		}
+	}
+	fs.allowed = append(fs.allowed, abs)
+	return nil
+}
+
+// Get reads the path's content from your local filesystem
+// if it is allowed by the sandbox.
+func (fs *SandboxedFilesystemLoader) Get(path string) (io.Reader, error) {
+	realPath, err := fs.Path(path)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := ioutil.ReadFile(realPath)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf), nil
+}
+
+// Path resolves a filename relative to the base directory
+// and returns its real path (symlinks resolved) if it is allowed by the sandbox.
+// An AccessDeniedError is returned otherwise.
+func (fs *SandboxedFilesystemLoader) Path(name string) (string, error) {
+	path, err := fs.FilesystemLoader.Path(name)
+	if err != nil {
+		return "", err
+	}
+	path = filepath.Clean(path)
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// Don't disclose anything about files outside of the sandbox
+		if !fs.isAllowed(path, false) {
+			return "", &AccessDeniedError{Name: name, Path: path}
+		}
+		return "", err
+	}
+	if !fs.isAllowed(realPath, true) {
+		return "", &AccessDeniedError{Name: name, Path: path}
+	}
+	return realPath, nil
+}
+
+// Version implements Versioner from the file modification time
+// if it is allowed by the sandbox.
+func (fs *SandboxedFilesystemLoader) Version(name string) (string, error) {
+	realPath, err := fs.Path(name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return "", err
+	}
+	return fileVersion(info), nil
+}
+
+// ListTemplates implements Lister by walking the base directory.
+// The templates denied by the sandbox (ie. symlinks escaping it) are not listed.
+func (fs *SandboxedFilesystemLoader) ListTemplates(extensions ...string) ([]string, error) {
+	names, err := fs.FilesystemLoader.ListTemplates(extensions...)
+	if err != nil {
+		return nil, err
+	}
+	allowed := names[:0]
+	for _, name := range names {
+		if _, err := fs.Path(name); err == nil {
+			allowed = append(allowed, name)
+		}
+	}
+	return allowed, nil
+}
+
+// isAllowed checks a path against the base directory and the whitelist.
+// The whitelisted directories are resolved first if resolve is true.
+func (fs *SandboxedFilesystemLoader) isAllowed(path string, resolve bool) bool {
+	entries := append([]string{fs.root}, fs.allowed...)
+	for _, entry := range entries {
+		if isPattern(entry) {
+			if matched, _ := filepath.Match(entry, path); matched {
+				return true
+			}
+			continue
+		}
+		if resolve {
+			dir, err := filepath.EvalSymlinks(entry)
+			if err != nil {
+				continue
+			}
+			entry = dir
+		}
+		if isWithin(entry, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPattern returns true if path contains any glob meta character
+func isPattern(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
+
+// isWithin returns true if path is dir or one of its descendants
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,104 @@
+package loaders
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FSLoader loads the templates from an fs.FS
+// (ie. an embed.FS, an fstest.MapFS or a zip.Reader).
+// Template names are slash-separated paths relative to the loader root.
+type FSLoader struct {
+	fsys fs.FS
+}
+
+// MustNewFSLoader creates a new FSLoader instance
+// and panics if there's any error during instantiation. The parameters
+// are the same like NewFSLoader.
+func MustNewFSLoader(fsys fs.FS, dir string) *FSLoader {
+	loader, err := NewFSLoader(fsys, dir)
+	if err != nil {
+		log.Panic(err)
+	}
+	return loader
+}
+
+// NewFSLoader creates a new FSLoader loading the templates from fsys.
+// If dir is given, the templates are loaded from this sub-directory of fsys
+// (ie. "templates" for a "//go:embed templates" directive).
+func NewFSLoader(fsys fs.FS, dir string) (*FSLoader, error) {
+	if dir != "" && dir != "." {
+		fi, err := fs.Stat(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			return nil, errors.Errorf("The given path '%s' is not a directory.", dir)
+		}
+		sub, err := fs.Sub(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+	return &FSLoader{fsys: fsys}, nil
+}
+
+// Get reads the template content from the underlying fs.FS.
+func (l *FSLoader) Get(name string) (io.Reader, error) {
+	buf, err := fs.ReadFile(l.fsys, l.path(name))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf), nil
+}
+
+// Version implements Versioner from the file modification time
+// as reported by the fs.FS (embed.FS always reports the same one).
+func (l *FSLoader) Version(name string) (string, error) {
+	info, err := fs.Stat(l.fsys, l.path(name))
+	if err != nil {
+		return "", err
+	}
+	return fileVersion(info), nil
+}
+
+// Resolve returns the name of the template loaded as name from the parent template.
+// Names starting with "./" or "../" are relative to the parent template directory,
+// any other name is relative to the loader root.
+func (l *FSLoader) Resolve(parent, name string) string {
+	return resolveRelative(parent, name)
+}
+
+// ListTemplates implements Lister by walking the whole fs.FS.
+func (l *FSLoader) ListTemplates(extensions ...string) ([]string, error) {
+	names := []string{}
+	err := fs.WalkDir(l.fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && hasExtension(name, extensions) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to list templates")
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// path converts a template name into an fs.FS path.
+// Leading slashes are ignored, invalid paths (ie. outside of the root)
+// are rejected by the fs.FS itself.
+func (l *FSLoader) path(name string) string {
+	return path.Clean(strings.TrimLeft(name, "/"))
+}
@@ -1,7 +1,13 @@
 package loaders
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // TemplateLoader allows to implement a virtual file system.
@@ -13,3 +19,93 @@ type Loader interface {
 	// Get returns an io.Reader where the template's content can be read from.
 	Get(path string) (io.Reader, error)
 }
+
+// Resolver is implemented by the loaders resolving the template names
+// relative to the template loading them (ie. "./header.html").
+type Resolver interface {
+	// Resolve returns the name of the template loaded as name from the parent template
+	Resolve(parent, name string) string
+}
+
+// Lister is implemented by the loaders able to enumerate their templates
+type Lister interface {
+	// ListTemplates returns the sorted names of all the templates
+	// or only of those having one of the given extensions (ie. ".html" or "html").
+	ListTemplates(extensions ...string) ([]string, error)
+}
+
+// ListTemplates lists the templates of loader with the given extensions.
+// An error is returned if loader is not a Lister.
+func ListTemplates(loader Loader, extensions ...string) ([]string, error) {
+	lister, ok := loader.(Lister)
+	if !ok {
+		return nil, errors.Errorf("Loader %T can't list its templates", loader)
+	}
+	return lister.ListTemplates(extensions...)
+}
+
+// Versioner is implemented by the loaders able to tell when a template changes
+type Versioner interface {
+	// Version returns an opaque string changing whenever the template source changes
+	// (ie. its modification time) or an error if the template is missing.
+	Version(name string) (string, error)
+}
+
+// Version returns the version of the named template if loader is a Versioner.
+// An empty version is returned otherwise: the template is considered as never changing.
+func Version(loader Loader, name string) (string, error) {
+	versioner, ok := loader.(Versioner)
+	if !ok {
+		return "", nil
+	}
+	return versioner.Version(name)
+}
+
+// fileVersion builds a version from a file modification time and size
+func fileVersion(info os.FileInfo) string {
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// NotFoundError is returned when a loader does not have the requested template
+type NotFoundError struct {
+	Name string // The requested template name
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("Template '%s' not found", e.Name)
+}
+
+// IsNotFound returns true if err is (or has been caused by) a NotFoundError
+// or a file not existing
+func IsNotFound(err error) bool {
+	cause := errors.Cause(err)
+	if _, ok := cause.(*NotFoundError); ok {
+		return true
+	}
+	return os.IsNotExist(cause)
+}
+
+// hasExtension returns true if name has one of the extensions
+// or if there is no extension to filter on
+func hasExtension(name string, extensions []string) bool {
+	if len(extensions) == 0 {
+		return true
+	}
+	ext := path.Ext(name)
+	for _, extension := range extensions {
+		if ext == extension || ext == "."+extension {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRelative resolves the names starting with "./" or "../"
+// relative to the parent template directory.
+// Any other name is returned unchanged.
+func resolveRelative(parent, name string) string {
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		return path.Join(path.Dir(parent), name)
+	}
+	return name
+}
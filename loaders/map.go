@@ -0,0 +1,80 @@
+package loaders
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MapLoader loads the templates from memory.
+// Template names are slash-separated paths, like with the FSLoader.
+// It is safe for concurrent use.
+type MapLoader struct {
+	mu        sync.RWMutex
+	templates map[string]string
+	versions  map[string]int
+}
+
+// NewMapLoader creates a new MapLoader holding a copy of the templates,
+// mapping the template names to their sources.
+func NewMapLoader(templates map[string]string) *MapLoader {
+	loader := &MapLoader{
+		templates: make(map[string]string, len(templates)),
+		versions:  map[string]int{},
+	}
+	for name, source := range templates {
+		loader.templates[name] = source
+	}
+	return loader
+}
+
+// Set adds or replaces a template
+func (l *MapLoader) Set(name, source string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.templates[name] = source
+	l.versions[name]++
+}
+
+// Get returns the template source or a NotFoundError if there is none.
+func (l *MapLoader) Get(name string) (io.Reader, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	source, ok := l.templates[name]
+	if !ok {
+		return nil, &NotFoundError{Name: name}
+	}
+	return strings.NewReader(source), nil
+}
+
+// Version implements Versioner, the version changes each time the template is Set.
+func (l *MapLoader) Version(name string) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if _, ok := l.templates[name]; !ok {
+		return "", &NotFoundError{Name: name}
+	}
+	return strconv.Itoa(l.versions[name]), nil
+}
+
+// Resolve returns the name of the template loaded as name from the parent template.
+// Names starting with "./" or "../" are relative to the parent template directory.
+func (l *MapLoader) Resolve(parent, name string) string {
+	return resolveRelative(parent, name)
+}
+
+// ListTemplates implements Lister.
+func (l *MapLoader) ListTemplates(extensions ...string) ([]string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	names := []string{}
+	for name := range l.templates {
+		if hasExtension(name, extensions) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,205 @@
+package gonja_test
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+type failingLoader struct{}
+
+func (failingLoader) Get(name string) (io.Reader, error) {
+	return nil, errors.New("broken loader")
+}
+
+var loadersContext = map[string]interface{}{"name": "john", "value": "john"}
+
+func TestMapLoader(t *testing.T) {
+	assert := assert.New(t)
+	loader := loaders.NewMapLoader(map[string]string{
+		"pages/index.html":   `{% extends "base.html" %}{% block content %}{% include "./hello.html" %}{% endblock %}`,
+		"pages/hello.html":   `Hello {{ name }}`,
+		"base.html":          `<p>{% block content %}{% endblock %}</p>`,
+		"pages/replaced.txt": `before`,
+	})
+	out, err := tu.RenderFile(t, tu.NewEnv(loader), "pages/index.html", loadersContext)
+	assert.Nil(err)
+	assert.Equal("<p>Hello john</p>", out)
+
+	loader.Set("pages/replaced.txt", "after")
+	out, err = tu.RenderFile(t, tu.NewEnv(loader), "pages/replaced.txt", loadersContext)
+	assert.Nil(err)
+	assert.Equal("after", out)
+
+	_, err = loader.Get("missing.html")
+	if assert.NotNil(err) {
+		assert.True(loaders.IsNotFound(err))
+		assert.Equal("Template 'missing.html' not found", err.Error())
+	}
+}
+
+func TestChoiceLoader(t *testing.T) {
+	assert := assert.New(t)
+	theme := loaders.NewMapLoader(map[string]string{
+		"header.html": `theme header`,
+	})
+	defaults := loaders.NewMapLoader(map[string]string{
+		"header.html": `default header`,
+		"footer.html": `default footer`,
+		"page.html":   `{% include "header.html" %}|{% include "footer.html" %}|{% include "notes.txt" %}`,
+	})
+	files := loaders.MustNewFileSystemLoader("testData/autoescape")
+	loader := loaders.NewChoiceLoader(theme, defaults, files)
+
+	out, err := tu.RenderFile(t, tu.NewEnv(loader), "page.html", loadersContext)
+	assert.Nil(err)
+	assert.Equal("theme header|default footer|john", out)
+
+	_, err = loader.Get("missing.html")
+	if assert.NotNil(err) {
+		assert.True(loaders.IsNotFound(err))
+	}
+}
+
+func TestChoiceLoaderStopsOnError(t *testing.T) {
+	loader := loaders.NewChoiceLoader(
+		loaders.NewMapLoader(nil),
+		failingLoader{},
+		loaders.NewMapLoader(map[string]string{"page.html": "page"}),
+	)
+	_, err := loader.Get("page.html")
+	if assert.NotNil(t, err) {
+		assert.False(t, loaders.IsNotFound(err))
+		assert.Equal(t, "broken loader", err.Error())
+	}
+}
+
+func TestPrefixLoader(t *testing.T) {
+	assert := assert.New(t)
+	loader := loaders.NewPrefixLoader(map[string]loaders.Loader{
+		"admin": loaders.NewMapLoader(map[string]string{
+			"index.html": `{% extends "./layout.html" %}{% block title %}Admin{% endblock %}`,
+			"layout.html": `<h1>{% block title %}{% endblock %}</h1>` +
+				`{% include "site/footer.html" %}`,
+		}),
+		"site": loaders.NewMapLoader(map[string]string{
+			"footer.html": `footer`,
+		}),
+	})
+
+	out, err := tu.RenderFile(t, tu.NewEnv(loader), "admin/index.html", loadersContext)
+	assert.Nil(err)
+	assert.Equal("<h1>Admin</h1>footer", out)
+
+	for _, name := range []string{"blog/index.html", "index.html", "site/missing.html"} {
+		_, err := loader.Get(name)
+		if assert.NotNil(err, name) {
+			assert.True(loaders.IsNotFound(err), name)
+		}
+	}
+}
+
+func TestLoadersComposition(t *testing.T) {
+	overrides := loaders.NewPrefixLoader(map[string]loaders.Loader{
+		"theme": loaders.NewMapLoader(map[string]string{"notes.txt": "theme notes"}),
+	})
+	loader := loaders.NewChoiceLoader(overrides, loaders.MustNewFSLoader(embedded, "testData/autoescape"))
+
+	reader, err := loader.Get("theme/notes.txt")
+	if assert.Nil(t, err) {
+		content, _ := ioutil.ReadAll(reader)
+		assert.Equal(t, "theme notes", string(content))
+	}
+	out, err := tu.RenderFile(t, tu.NewEnv(loader), "notes.txt", loadersContext)
+	assert.Nil(t, err)
+	assert.Equal(t, "john", out)
+}
+
+func TestListTemplates(t *testing.T) {
+	themes := loaders.NewMapLoader(map[string]string{
+		"base.html":    "",
+		"page.html":    "",
+		"style.css":    "",
+		"partials/nav": "",
+	})
+	defaults := loaders.NewMapLoader(map[string]string{
+		"base.html":  "",
+		"index.html": "",
+	})
+	for _, tc := range []struct {
+		name       string
+		loader     loaders.Loader
+		extensions []string
+		expected   []string
+	}{
+		{"map", themes, nil, []string{"base.html", "page.html", "partials/nav", "style.css"}},
+		{"map filtered", themes, []string{".html", "css"}, []string{"base.html", "page.html", "style.css"}},
+		{"filesystem", loaders.MustNewFileSystemLoader("testData/autoescape"), []string{"html", "js"},
+			[]string{"page.html", "script.js"}},
+		{"fs", loaders.MustNewFSLoader(embedded, "testData"), []string{".xml"}, []string{"autoescape/feed.xml"}},
+		{"choice", loaders.NewChoiceLoader(themes, defaults), []string{".html"},
+			[]string{"base.html", "index.html", "page.html"}},
+		{"prefix", loaders.NewPrefixLoader(map[string]loaders.Loader{"theme": themes, "default": defaults}), []string{".html"},
+			[]string{"default/base.html", "default/index.html", "theme/base.html", "theme/page.html"}},
+	} {
+		test := tc
+		t.Run(test.name, func(t *testing.T) {
+			names, err := loaders.ListTemplates(test.loader, test.extensions...)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
+func TestListTemplatesUnsupported(t *testing.T) {
+	for _, loader := range []loaders.Loader{
+		failingLoader{},
+		loaders.NewChoiceLoader(loaders.NewMapLoader(nil), failingLoader{}),
+	} {
+		_, err := loaders.ListTemplates(loader)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "can't list its templates")
+		}
+	}
+}
+
+func TestCompileAll(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(loaders.NewMapLoader(map[string]string{
+		"base.html":    `{% block content %}{% endblock %}`,
+		"page.html":    `{% extends "base.html" %}{% block content %}page{% endblock %}`,
+		"broken.html":  `{% if %}`,
+		"missing.html": `{% include "unknown.html" %}`,
+		"notes.txt":    `{{ unclosed`,
+	}))
+
+	err := env.CompileAll(".html")
+	errs, ok := err.(gonja.CompileErrors)
+	if assert.True(ok, "expected CompileErrors, got %v", err) && assert.Len(errs, 2) {
+		te, ok := exec.AsTemplateError(errs[0])
+		if assert.True(ok) {
+			assert.Equal("broken.html", te.Name)
+		}
+		te, ok = exec.AsTemplateError(errs[1])
+		if assert.True(ok) {
+			assert.Equal("missing.html", te.Name)
+		}
+		assert.Len(strings.Split(err.Error(), "\n"), 2)
+	}
+	for name, expected := range map[string]bool{"base.html": true, "page.html": true, "notes.txt": false} {
+		_, cached := env.Cache.Get(name)
+		assert.Equal(expected, cached, name)
+	}
+
+	assert.Nil(tu.NewEnv(loaders.NewMapLoader(nil)).CompileAll())
+	assert.NotNil(tu.NewEnv(failingLoader{}).CompileAll())
+}
@@ -0,0 +1,39 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+)
+
+var loopControlsErrorCases = []struct {
+	name     string
+	source   string
+	expected string
+}{
+	{"break outside loop", "{% break %}",
+		`parse error in template 'string' (Line: 1 Col: 10, near "%}"): 'break' statement is only allowed within a loop.`},
+	{"continue outside loop", "{% if true %}{% continue %}{% endif %}",
+		`parse error in template 'string' (Line: 1 Col: 26, near "%}"): 'continue' statement is only allowed within a loop.`},
+	{"break in for else", "{% for i in items %}{% else %}{% break %}{% endfor %}",
+		`parse error in template 'string' (Line: 1 Col: 40, near "%}"): 'break' statement is only allowed within a loop.`},
+	{"break in macro", "{% for i in items %}{% macro m() %}{% break %}{% endmacro %}{% endfor %}",
+		`parse error in template 'string' (Line: 1 Col: 45, near "%}"): 'break' statement is only allowed within a loop.`},
+	{"continue with arguments", "{% for i in items %}{% continue 2 %}{% endfor %}",
+		`parse error in template 'string' (Line: 1 Col: 33, near "2"): 'continue' statement does not take any argument.`},
+}
+
+func TestLoopControlsErrors(t *testing.T) {
+	for _, lc := range loopControlsErrorCases {
+		test := lc
+		t.Run(test.name, func(t *testing.T) {
+			_, err := gonja.FromString(test.source)
+			if assert.IsType(t, &exec.TemplateError{}, err) {
+				assert.Equal(t, test.expected, err.Error())
+			}
+		})
+	}
+}
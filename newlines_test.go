@@ -0,0 +1,44 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+// The conversions to "\r\n" are covered by testData/newlines
+var newlinesCases = []struct {
+	name     string
+	source   string
+	nl       string
+	trim     bool
+	lstrip   bool
+	keep     bool
+	expected string
+}{
+	{"windows to unix", "a\r\nb\r\n", "\n", false, false, true, "a\nb\n"},
+	{"trailing newline stripped", "a\r\n", "\r\n", false, false, false, "a"},
+	{"trailing old mac newline stripped", "a\r", "\r", false, false, false, "a"},
+	{"only one trailing newline stripped", "a\r\n\r\n", "\r\n", false, false, false, "a\r\n"},
+	{"trim blocks with old mac newline", "{% if true %}\ra{% endif %}\rb", "\r", true, false, true, "ab"},
+	{"lstrip blocks with old mac newline", "a\r  {% if true %}b{% endif %}", "\r", false, true, true, "a\rb"},
+}
+
+func TestNewlineSequence(t *testing.T) {
+	for _, nc := range newlinesCases {
+		test := nc
+		t.Run(test.name, func(t *testing.T) {
+			env := tu.NewEnv(nil)
+			env.NewlineSequence = test.nl
+			env.TrimBlocks = test.trim
+			env.LstripBlocks = test.lstrip
+			env.KeepTrailingNewline = test.keep
+			out, err := tu.Render(t, env, test.source, nil)
+			if assert.Nil(t, err) {
+				assert.Equal(t, test.expected, out)
+			}
+		})
+	}
+}
@@ -49,6 +49,15 @@ type Template struct {
 	Blocks BlockSet
 	Macros map[string]*Macro
 	Parent *Template
+
+	// The autoescaping mode selected from the template name, if any
+	Autoescape *Autoescape
+}
+
+// Autoescape is an autoescaping mode
+type Autoescape struct {
+	Enabled bool
+	Escaper string // The escaper name, the default one if empty
 }
 
 func (t *Template) Position() *tokens.Token { return t.Nodes[0].Position() }
@@ -161,6 +170,24 @@ func (expr *TestExpression) Position() *tokens.Token {
 	return expr.Expression.Position()
 }
 
+// InlineIfExpression is an inline condition: True if Condition else False
+type InlineIfExpression struct {
+	Location  *tokens.Token // The "if" keyword
+	Condition Expression
+	True      Expression
+	False     Expression // nil if there is no else clause
+}
+
+func (expr *InlineIfExpression) String() string {
+	t := expr.Position()
+
+	return fmt.Sprintf("InlineIfExpression(Condition=%s True=%s False=%s Line=%d Col=%d)",
+		expr.Condition, expr.True, optional(expr.False), t.Line, t.Col)
+}
+func (expr *InlineIfExpression) Position() *tokens.Token {
+	return expr.Location
+}
+
 type TestCall struct {
 	Token *tokens.Token
 
@@ -308,18 +335,23 @@ func (c *Call) String() string {
 	return fmt.Sprintf("Call(Args=%s Kwargs=%s Line=%d Col=%d)", c.Args, c.Kwargs, t.Line, t.Col)
 }
 
+// Getitem is a subscript: Node[Arg], Node[Index] or Node[Key].
+// Key is only set when the subscript is not a string or integer literal.
 type Getitem struct {
 	Location *tokens.Token
 	Node     Node
 	Arg      string
 	Index    int
+	Key      Expression
 }
 
 func (g *Getitem) Position() *tokens.Token { return g.Location }
 func (g *Getitem) String() string {
 	t := g.Position()
 	var param string
-	if g.Arg != "" {
+	if g.Key != nil {
+		param = fmt.Sprintf(`Key=%s`, g.Key)
+	} else if g.Arg != "" {
 		param = fmt.Sprintf(`Arg=%s`, g.Arg)
 	} else {
 		param = fmt.Sprintf(`Index=%s`, strconv.Itoa(g.Index))
@@ -327,6 +359,31 @@ func (g *Getitem) String() string {
 	return fmt.Sprintf("Getitem(Node=%s %s Line=%d Col=%d)", g.Node, param, t.Line, t.Col)
 }
 
+// Slice is a python-style slicing: Node[Start:Stop:Step].
+// Missing bounds are nil.
+type Slice struct {
+	Location *tokens.Token
+	Node     Node
+	Start    Expression
+	Stop     Expression
+	Step     Expression
+}
+
+func (s *Slice) Position() *tokens.Token { return s.Location }
+func (s *Slice) String() string {
+	t := s.Position()
+	return fmt.Sprintf("Slice(Node=%s Start=%s Stop=%s Step=%s Line=%d Col=%d)",
+		s.Node, optional(s.Start), optional(s.Stop), optional(s.Step), t.Line, t.Col)
+}
+
+// optional returns the representation of an optional expression, empty if missing
+func optional(expr Expression) string {
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
+
 type Getattr struct {
 	Location *tokens.Token
 	Node     Node
@@ -393,11 +450,12 @@ func (op BinOperator) Position() *tokens.Token { return op.Token }
 func (op BinOperator) String() string          { return op.Token.String() }
 
 type StatementBlock struct {
-	Location *tokens.Token
-	Name     string
-	Stmt     Statement
-	Trim     *Trim
-	LStrip   bool
+	Location      *tokens.Token
+	Name          string
+	Stmt          Statement
+	Trim          *Trim
+	LStrip        bool
+	LineStatement bool // Whether the statement is a line statement
 }
 
 func (s StatementBlock) Position() *tokens.Token { return s.Location }
@@ -409,11 +467,12 @@ func (s StatementBlock) String() string {
 }
 
 type Wrapper struct {
-	Location *tokens.Token
-	Nodes    []Node
-	EndTag   string
-	Trim     *Trim
-	LStrip   bool
+	Location      *tokens.Token
+	Nodes         []Node
+	EndTag        string
+	Trim          *Trim
+	LStrip        bool
+	LineStatement bool // Whether the end tag is a line statement
 }
 
 func (w Wrapper) Position() *tokens.Token { return w.Location }
@@ -1,6 +1,8 @@
 package nodes
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 )
 
@@ -17,6 +19,15 @@ type Visitor interface {
 // }
 
 func Walk(v Visitor, node Node) error {
+	return WalkContext(context.Background(), v, node)
+}
+
+// WalkContext walks the AST like Walk but stops as soon as ctx is done,
+// returning the context error.
+func WalkContext(ctx context.Context, v Visitor, node Node) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	v, err := v.Visit(node)
 	if err != nil {
 		return err
@@ -28,13 +39,13 @@ func Walk(v Visitor, node Node) error {
 	switch n := node.(type) {
 	case *Template:
 		for _, node := range n.Nodes {
-			if err := Walk(v, node); err != nil {
+			if err := WalkContext(ctx, v, node); err != nil {
 				return err
 			}
 		}
 	case *Wrapper:
 		for _, node := range n.Nodes {
-			if err := Walk(v, node); err != nil {
+			if err := WalkContext(ctx, v, node); err != nil {
 				return err
 			}
 		}
@@ -13,7 +13,7 @@ func (p *Parser) ParseComment() (*nodes.Comment, error) {
 		"current": p.Current(),
 	}).Trace("ParseComment")
 
-	tok := p.Match(tokens.CommentBegin)
+	tok := p.Match(tokens.CommentBegin, tokens.LinecommentBegin)
 	if tok == nil {
 		msg := fmt.Sprintf(`Expected '%s' , got %s`, p.Config.CommentStartString, p.Current())
 		return nil, p.Error(msg, p.Current())
@@ -21,22 +21,25 @@ func (p *Parser) ParseComment() (*nodes.Comment, error) {
 
 	comment := &nodes.Comment{
 		Start: tok,
-		Trim:  &nodes.Trim{},
+		Trim: &nodes.Trim{
+			Left: isTrimLeft(tok),
+		},
 	}
 
-	tok = p.Match(tokens.Data)
+	tok = p.Match(tokens.Data, tokens.Linecomment)
 	if tok == nil {
 		comment.Text = ""
 	} else {
 		comment.Text = tok.Val
 	}
 
-	tok = p.Match(tokens.CommentEnd)
+	tok = p.Match(tokens.CommentEnd, tokens.LinecommentEnd)
 	if tok == nil {
 		msg := fmt.Sprintf(`Expected '%s' , got %s`, p.Config.CommentEndString, p.Current())
 		return nil, p.Error(msg, p.Current())
 	}
 	comment.End = tok
+	comment.Trim.Right = isTrimRight(tok)
 
 	log.WithFields(log.Fields{
 		"node": comment,
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+
 	"github.com/noirbizarre/gonja/nodes"
 	"github.com/noirbizarre/gonja/tokens"
 	log "github.com/sirupsen/logrus"
@@ -53,6 +55,11 @@ func (p *Parser) ParseExpression() (nodes.Expression, error) {
 		return nil, err
 	}
 
+	expr, err = p.parseInlineIf(expr)
+	if err != nil {
+		return nil, err
+	}
+
 	expr, err = p.ParseFilterExpression(expr)
 	if err != nil {
 		return nil, err
@@ -64,6 +71,51 @@ func (p *Parser) ParseExpression() (nodes.Expression, error) {
 	return expr, nil
 }
 
+// ParseExpressionWithoutCondition parses an expression with optionnal filters
+// but stops before an inline condition, (ie. the for loop iterable followed by its filter).
+func (p *Parser) ParseExpressionWithoutCondition() (nodes.Expression, error) {
+	expr, err := p.ParseLogicalExpression()
+	if err != nil {
+		return nil, err
+	}
+	return p.ParseFilterExpression(expr)
+}
+
+// parseInlineIf parses the optionnal inline conditions following expr:
+// expr if condition [else other]
+func (p *Parser) parseInlineIf(expr nodes.Expression) (nodes.Expression, error) {
+	log.WithFields(log.Fields{
+		"current": p.Current(),
+	}).Trace("parseInlineIf")
+
+	for tok := p.MatchName("if"); tok != nil; tok = p.MatchName("if") {
+		condition, err := p.ParseLogicalExpression()
+		if err != nil {
+			return nil, err
+		}
+		inlineIf := &nodes.InlineIfExpression{
+			Location:  tok,
+			Condition: condition,
+			True:      expr,
+		}
+		if p.MatchName("else") != nil {
+			other, err := p.ParseLogicalExpression()
+			if err != nil {
+				return nil, err
+			}
+			// Chained conditions are right associative
+			other, err = p.parseInlineIf(other)
+			if err != nil {
+				return nil, err
+			}
+			inlineIf.False = other
+		}
+		expr = inlineIf
+	}
+
+	return expr, nil
+}
+
 func (p *Parser) ParseExpressionNode() (nodes.Node, error) {
 	log.WithFields(log.Fields{
 		"current": p.Current(),
@@ -71,13 +123,13 @@ func (p *Parser) ParseExpressionNode() (nodes.Node, error) {
 
 	tok := p.Match(tokens.VariableBegin)
 	if tok == nil {
-		return nil, p.Error("'{{' expected here", p.Current())
+		return nil, p.Error(fmt.Sprintf(`'%s' expected here`, p.Config.VariableStartString), p.Current())
 	}
 
 	node := &nodes.Output{
 		Start: tok,
 		Trim: &nodes.Trim{
-			Left: tok.Val[len(tok.Val)-1] == '-',
+			Left: isTrimLeft(tok),
 		},
 	}
 
@@ -92,10 +144,10 @@ func (p *Parser) ParseExpressionNode() (nodes.Node, error) {
 
 	tok = p.Match(tokens.VariableEnd)
 	if tok == nil {
-		return nil, p.Error("'}}' expected here", p.Current())
+		return nil, p.Error(fmt.Sprintf(`'%s' expected here`, p.Config.VariableEndString), p.Current())
 	}
 	node.End = tok
-	node.Trim.Right = tok.Val[0] == '-'
+	node.Trim.Right = isTrimRight(tok)
 
 	log.WithFields(log.Fields{
 		"node": node,
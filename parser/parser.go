@@ -26,7 +26,19 @@ type Parser struct {
 	Template       *nodes.Template
 	Statements     map[string]StatementParser
 	Level          int8
+	Loops          int // Number of loops enclosing the current position, for loop controls
 	TemplateParser TemplateParser
+
+	// TemplateResolver resolves the names of the loaded templates, if set
+	TemplateResolver TemplateResolver
+
+	// Recover enables the recovery mode: syntax errors are collected in Errors
+	// and parsing resumes at the next tag instead of stopping on the first one.
+	Recover bool
+	Errors  []error
+
+	failed    []string // The statements which failed before parsing their body, in recovery mode
+	truncated bool     // True if a lexing error ended the template, in recovery mode
 }
 
 // Creates a new parser to parse tokens.
@@ -60,6 +72,8 @@ func Parse(input string) (*nodes.Template, error) {
 	return p.Parse()
 }
 
+// Parse parses the whole template.
+// In recovery mode, the syntax errors are returned as an ErrorList.
 func (p *Parser) Parse() (*nodes.Template, error) {
 	// for p.state = parseProg; p.state != nil; {
 	// 	p.state = p.state(p)
@@ -77,7 +91,11 @@ func (p *Parser) Parse() (*nodes.Template, error) {
 	// 	p.tokens = append(p.tokens, token)
 	// }
 
-	return p.ParseTemplate()
+	tpl, err := p.ParseTemplate()
+	if err == nil && len(p.Errors) > 0 {
+		return tpl, ErrorList(p.Errors)
+	}
+	return tpl, err
 }
 
 // Consume one token. It will be gone forever.
@@ -165,6 +183,21 @@ func (p *Parser) PeekName(names ...string) *tokens.Token {
 	return nil
 }
 
+// isTrimLeft returns true if an opening token holds the trim marker
+func isTrimLeft(tok *tokens.Token) bool {
+	return tok.Type != tokens.LinestatementBegin && strings.HasSuffix(tok.Val, "-")
+}
+
+// isLStrip returns true if an opening token disables lstrip_blocks
+func isLStrip(tok *tokens.Token) bool {
+	return tok.Type != tokens.LinestatementBegin && strings.HasSuffix(tok.Val, "+")
+}
+
+// isTrimRight returns true if a closing token holds the trim marker
+func isTrimRight(tok *tokens.Token) bool {
+	return tok.Type != tokens.LinestatementEnd && strings.HasPrefix(tok.Val, "-")
+}
+
 // WrapUntil wraps all nodes between starting tag and "{% endtag %}" and provides
 // one simple interface to execute the wrapped nodes.
 // It returns a parser to process provided arguments to the tag.
@@ -175,10 +208,11 @@ func (p *Parser) WrapUntil(names ...string) (*nodes.Wrapper, *Parser, error) {
 	}
 
 	var args []*tokens.Token
+	failed := len(p.failed)
 
 	for !p.Stream.End() {
 		// New tag, check whether we have to stop wrapping here
-		if begin := p.Match(tokens.BlockBegin); begin != nil {
+		if begin := p.Match(tokens.BlockBegin, tokens.LinestatementBegin); begin != nil {
 			ident := p.Peek(tokens.Name)
 
 			if ident != nil {
@@ -193,20 +227,25 @@ func (p *Parser) WrapUntil(names ...string) (*nodes.Wrapper, *Parser, error) {
 				}
 
 				// We only process the tag if we've found an end tag
-				if found {
+				// which doesn't close a failed statement
+				if found && p.failedIndex(ident.Val, failed) < 0 {
 					// Okay, endtag found.
 					p.Consume() // '{%' tagname
-					wrapper.Trim.Left = begin.Val[len(begin.Val)-1] == '-'
-					wrapper.LStrip = begin.Val[len(begin.Val)-1] == '+'
+					wrapper.Trim.Left = isTrimLeft(begin)
+					wrapper.LStrip = isLStrip(begin)
+					wrapper.LineStatement = begin.Type == tokens.LinestatementBegin
 
 					for {
-						if end := p.Match(tokens.BlockEnd); end != nil {
+						if end := p.Match(tokens.BlockEnd, tokens.LinestatementEnd); end != nil {
 							// Okay, end the wrapping here
 							wrapper.EndTag = ident.Val
-							wrapper.Trim.Right = end.Val[0] == '-'
+							wrapper.Trim.Right = isTrimRight(end)
 							stream := tokens.NewStream(args)
 							return wrapper, NewParser(p.Name, p.Config, stream), nil
 						}
+						if p.Stream.IsError() {
+							return nil, nil, p.Error(p.Current().Val, p.Current())
+						}
 						t := p.Next()
 						// p.Consume()
 						if t == nil {
@@ -220,22 +259,72 @@ func (p *Parser) WrapUntil(names ...string) (*nodes.Wrapper, *Parser, error) {
 		}
 
 		// Otherwise process next element to be wrapped
+		start := p.Current()
 		node, err := p.parseDocElement()
 		if err != nil {
+			if p.resync(start, err) {
+				continue
+			}
 			return nil, nil, err
 		}
-		wrapper.Nodes = append(wrapper.Nodes, node)
+		if node != nil {
+			wrapper.Nodes = append(wrapper.Nodes, node)
+		}
 	}
 
 	return nil, nil, p.Error(fmt.Sprintf("Unexpected EOF, expected tag %s.", strings.Join(names, " or ")),
 		p.Current())
 }
 
+// resync records err and skips tokens up to the next tag, comment or expression
+// so that parsing can resume after an error.
+// start is the token the failed parsing started at.
+// It returns false if the parser is not in recovery mode.
+func (p *Parser) resync(start *tokens.Token, err error) bool {
+	if !p.Recover {
+		return false
+	}
+	if p.Stream.IsError() {
+		// Lexing errors take precedence as they most likely caused the parsing one
+		err = p.Error(p.Current().Val, p.Current())
+		if next := p.Stream.Peek(); next == nil || next.Type == tokens.EOF {
+			p.truncated = true
+		}
+		p.Errors = append(p.Errors, err)
+	} else if !p.truncated || !p.Stream.EOF() {
+		// Reaching the end of a template truncated by the lexer is not another error
+		p.Errors = append(p.Errors, err)
+	}
+	if p.Current() == start && !p.Stream.EOF() {
+		p.Consume()
+	}
+	for p.Peek(
+		tokens.BlockBegin, tokens.LinestatementBegin,
+		tokens.VariableBegin,
+		tokens.CommentBegin, tokens.LinecommentBegin,
+		tokens.EOF,
+	) == nil {
+		p.Consume()
+	}
+	return true
+}
+
+// failedIndex returns the index of the failed statement closed by the end tag name,
+// searching the statements failed since from, or -1 if there is none.
+func (p *Parser) failedIndex(name string, from int) int {
+	for idx := len(p.failed) - 1; idx >= from; idx-- {
+		if name == "end"+p.failed[idx] {
+			return idx
+		}
+	}
+	return -1
+}
+
 // Skips all nodes between starting tag and "{% endtag %}"
 func (p *Parser) SkipUntil(names ...string) error {
 	for !p.End() {
 		// New tag, check whether we have to stop wrapping here
-		if p.Match(tokens.BlockBegin) != nil {
+		if p.Match(tokens.BlockBegin, tokens.LinestatementBegin) != nil {
 			ident := p.Peek(tokens.Name)
 
 			if ident != nil {
@@ -255,7 +344,7 @@ func (p *Parser) SkipUntil(names ...string) error {
 					p.Consume() // '{%' tagname
 
 					for {
-						if p.Match(tokens.BlockEnd) != nil {
+						if p.Match(tokens.BlockEnd, tokens.LinestatementEnd) != nil {
 							// Done skipping, exit.
 							return nil
 						}
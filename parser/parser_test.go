@@ -537,6 +537,124 @@ var testCases = []struct {
 			}},
 		}},
 	}}},
+	{"Test followed by logical expression", "{{ a is defined and b }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.BinaryExpression{}, attrs{
+			"Left": specs{nodes.TestExpression{}, attrs{
+				"Expression": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+				"Test": specs{nodes.TestCall{}, attrs{
+					"Name": val{"defined"},
+					"Args": slice{},
+				}},
+			}},
+			"Right":    specs{nodes.Name{}, attrs{"Name": _token("b")}},
+			"Operator": _binOp("and"),
+		}},
+	}}},
+	{"Test argument followed by logical expression", "{{ a is divisibleby 3 and b }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.BinaryExpression{}, attrs{
+			"Left": specs{nodes.TestExpression{}, attrs{
+				"Expression": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+				"Test": specs{nodes.TestCall{}, attrs{
+					"Name": val{"divisibleby"},
+					"Args": slice{_literal(nodes.Integer{}, int64(3))},
+				}},
+			}},
+			"Right":    specs{nodes.Name{}, attrs{"Name": _token("b")}},
+			"Operator": _binOp("and"),
+		}},
+	}}},
+	{"Test argument with attribute", "{{ a is sameas b.c }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.TestExpression{}, attrs{
+			"Expression": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"Test": specs{nodes.TestCall{}, attrs{
+				"Name": val{"sameas"},
+				"Args": slice{specs{nodes.Getattr{}, attrs{
+					"Node": specs{nodes.Name{}, attrs{"Name": _token("b")}},
+					"Attr": val{"c"},
+				}}},
+			}},
+		}},
+	}}},
+	{"Test without argument in inline if", "{{ 1 if a is defined else 2 }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.InlineIfExpression{}, attrs{
+			"Condition": specs{nodes.TestExpression{}, attrs{
+				"Expression": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+				"Test": specs{nodes.TestCall{}, attrs{
+					"Name": val{"defined"},
+					"Args": slice{},
+				}},
+			}},
+			"True":  _literal(nodes.Integer{}, int64(1)),
+			"False": _literal(nodes.Integer{}, int64(2)),
+		}},
+	}}},
+	{"Slice", "{{ a[1:b] }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.Slice{}, attrs{
+			"Node":  specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"Start": _literal(nodes.Integer{}, int64(1)),
+			"Stop":  specs{nodes.Name{}, attrs{"Name": _token("b")}},
+		}},
+	}}},
+	{"Slice with step only", "{{ a[::2] }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.Slice{}, attrs{
+			"Node": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"Step": _literal(nodes.Integer{}, int64(2)),
+		}},
+	}}},
+	{"Slice on a literal", "{{ 'abc'[1:] }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.Slice{}, attrs{
+			"Node":  _literal(nodes.String{}, "abc"),
+			"Start": _literal(nodes.Integer{}, int64(1)),
+		}},
+	}}},
+	{"Getitem with a variable", "{{ a[i] }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.Getitem{}, attrs{
+			"Node": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"Key":  specs{nodes.Name{}, attrs{"Name": _token("i")}},
+		}},
+	}}},
+	{"Getitem with a negative index", "{{ a[-1] }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.Getitem{}, attrs{
+			"Node": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"Key": specs{nodes.UnaryExpression{}, attrs{
+				"Negative": val{true},
+				"Term":     _literal(nodes.Integer{}, int64(1)),
+			}},
+		}},
+	}}},
+	{"Getitem on a list", "{{ [1, 2][0] }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.Getitem{}, attrs{
+			"Node": _literal(nodes.List{}, slice{
+				_literal(nodes.Integer{}, int64(1)),
+				_literal(nodes.Integer{}, int64(2)),
+			}),
+			"Index": val{int64(0)},
+		}},
+	}}},
+	{"Inline if", "{{ 1 if a else 2 }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.InlineIfExpression{}, attrs{
+			"Condition": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"True":      _literal(nodes.Integer{}, int64(1)),
+			"False":     _literal(nodes.Integer{}, int64(2)),
+		}},
+	}}},
+	{"Inline if without else", "{{ 1 if a }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.InlineIfExpression{}, attrs{
+			"Condition": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"True":      _literal(nodes.Integer{}, int64(1)),
+		}},
+	}}},
+	{"Chained inline if", "{{ 1 if a else 2 if b else 3 }}", specs{nodes.Output{}, attrs{
+		"Expression": specs{nodes.InlineIfExpression{}, attrs{
+			"Condition": specs{nodes.Name{}, attrs{"Name": _token("a")}},
+			"True":      _literal(nodes.Integer{}, int64(1)),
+			"False": specs{nodes.InlineIfExpression{}, attrs{
+				"Condition": specs{nodes.Name{}, attrs{"Name": _token("b")}},
+				"True":      _literal(nodes.Integer{}, int64(2)),
+				"False":     _literal(nodes.Integer{}, int64(3)),
+			}},
+		}},
+	}}},
 }
 
 // func parseText(text string) (*nodeDocument, *Error) {
@@ -19,8 +19,8 @@ func (p *Parser) ParseStatement() (nodes.Statement, error) {
 		"current": p.Current(),
 	}).Trace("ParseStatement")
 
-	if p.Match(tokens.BlockBegin) == nil {
-		return nil, p.Error("'{%' expected here", p.Current())
+	if p.Match(tokens.BlockBegin, tokens.LinestatementBegin) == nil {
+		return nil, p.Error(fmt.Sprintf(`'%s' expected here`, p.Config.BlockStartString), p.Current())
 	}
 
 	name := p.Match(tokens.Name)
@@ -41,7 +41,7 @@ func (p *Parser) ParseStatement() (nodes.Statement, error) {
 	// }
 
 	var args []*tokens.Token
-	for p.Peek(tokens.BlockEnd) == nil && !p.Stream.End() {
+	for p.Peek(tokens.BlockEnd, tokens.LinestatementEnd, tokens.Error) == nil && !p.Stream.End() {
 		// Add token to args
 		args = append(args, p.Next())
 		// p.Consume() // next token
@@ -52,7 +52,7 @@ func (p *Parser) ParseStatement() (nodes.Statement, error) {
 	// 	return nil, p.Error("Unexpectedly reached EOF, no statement end found.", p.lastToken)
 	// }
 
-	if p.Match(tokens.BlockEnd) == nil {
+	if p.Match(tokens.BlockEnd, tokens.LinestatementEnd) == nil {
 		return nil, p.Error(fmt.Sprintf(`Expected end of block "%s"`, p.Config.BlockEndString), p.Current())
 	}
 
@@ -75,7 +75,7 @@ func (p *Parser) ParseStatementBlock() (*nodes.StatementBlock, error) {
 		"current": p.Current(),
 	}).Trace("ParseStatementBlock")
 
-	begin := p.Match(tokens.BlockBegin)
+	begin := p.Match(tokens.BlockBegin, tokens.LinestatementBegin)
 	if begin == nil {
 		return nil, errors.Errorf(`Expected "%s" got "%s"`, p.Config.BlockStartString, p.Current())
 	}
@@ -88,6 +88,14 @@ func (p *Parser) ParseStatementBlock() (*nodes.StatementBlock, error) {
 	// Check for the existing statement
 	stmtParser, exists := p.Statements[name.Val]
 	if !exists {
+		if idx := p.failedIndex(name.Val, 0); idx >= 0 {
+			// The end tag of a failed statement has already been reported with it
+			p.failed = append(p.failed[:idx], p.failed[idx+1:]...)
+			for p.Match(tokens.BlockEnd, tokens.LinestatementEnd) == nil && !p.Stream.End() {
+				p.Consume()
+			}
+			return nil, nil
+		}
 		// Does not exists
 		return nil, p.Error(fmt.Sprintf("Statement '%s' not found (or beginning not provided)", name.Val), name)
 	}
@@ -99,7 +107,7 @@ func (p *Parser) ParseStatementBlock() (*nodes.StatementBlock, error) {
 
 	log.Trace("args")
 	var args []*tokens.Token
-	for p.Peek(tokens.BlockEnd) == nil && !p.Stream.End() {
+	for p.Peek(tokens.BlockEnd, tokens.LinestatementEnd, tokens.Error) == nil && !p.Stream.End() {
 		log.Trace("for args")
 		// Add token to args
 		args = append(args, p.Next())
@@ -112,7 +120,7 @@ func (p *Parser) ParseStatementBlock() (*nodes.StatementBlock, error) {
 	// 	return nil, p.Error("Unexpectedly reached EOF, no statement end found.", p.lastToken)
 	// }
 
-	end := p.Match(tokens.BlockEnd)
+	end := p.Match(tokens.BlockEnd, tokens.LinestatementEnd)
 	if end == nil {
 		return nil, p.Error(fmt.Sprintf(`Expected end of block "%s"`, p.Config.BlockEndString), p.Current())
 	}
@@ -120,6 +128,8 @@ func (p *Parser) ParseStatementBlock() (*nodes.StatementBlock, error) {
 		"args": args,
 	}).Trace("Matched end block")
 
+	// Locate the end of the arguments at the closing delimiter
+	args = append(args, &tokens.Token{Type: tokens.EOF, Val: end.Val, Pos: end.Pos, Line: end.Line, Col: end.Col})
 	stream := tokens.NewStream(args)
 	log.WithFields(log.Fields{
 		"stream": stream,
@@ -134,19 +144,25 @@ func (p *Parser) ParseStatementBlock() (*nodes.StatementBlock, error) {
 
 	// p.template.level++
 	// defer func() { p.template.level-- }()
+	body := p.Current()
 	stmt, err := stmtParser(p, argParser)
 	if err != nil {
-		return nil, errors.Wrapf(err, `Unable to parse statement "%s"`, name.Val)
+		if p.Recover && p.Current() == body {
+			// The body, if any, is left unparsed with its end tag
+			p.failed = append(p.failed, name.Val)
+		}
+		return nil, &StatementError{Name: name.Val, Token: begin, cause: err}
 	}
 	log.Trace("got stmt and return")
 	return &nodes.StatementBlock{
 		Location: begin,
 		Name:     name.Val,
 		Stmt:     stmt,
-		LStrip:   begin.Val[len(begin.Val)-1] == '+',
+		LStrip:   isLStrip(begin),
 		Trim: &nodes.Trim{
-			Left:  begin.Val[len(begin.Val)-1] == '-',
-			Right: end.Val[0] == '-',
+			Left:  isTrimLeft(begin),
+			Right: isTrimRight(end),
 		},
+		LineStatement: begin.Type == tokens.LinestatementBegin,
 	}, nil
 }
@@ -7,6 +7,19 @@ import (
 
 type TemplateParser func(string) (*nodes.Template, error)
 
+// TemplateResolver resolves a template name loaded from the parent template
+type TemplateResolver func(parent, name string) string
+
+// ResolveTemplate resolves a template name loaded from the template being parsed
+// (ie. relative names like "./header.html").
+// The name is returned unchanged if there is no TemplateResolver.
+func (p *Parser) ResolveTemplate(name string) string {
+	if p.TemplateResolver == nil {
+		return name
+	}
+	return p.TemplateResolver(p.Name, name)
+}
+
 // Doc = { ( Filter | Tag | HTML ) }
 func (p *Parser) parseDocElement() (nodes.Node, error) {
 	t := p.Current()
@@ -19,12 +32,17 @@ func (p *Parser) parseDocElement() (nodes.Node, error) {
 	case tokens.EOF:
 		p.Consume()
 		return nil, nil
-	case tokens.CommentBegin:
+	case tokens.CommentBegin, tokens.LinecommentBegin:
 		return p.ParseComment()
 	case tokens.VariableBegin:
 		return p.ParseExpressionNode()
-	case tokens.BlockBegin:
-		return p.ParseStatementBlock()
+	case tokens.BlockBegin, tokens.LinestatementBegin:
+		stmt, err := p.ParseStatementBlock()
+		if stmt == nil {
+			// Avoid a non-nil interface holding a nil statement
+			return nil, err
+		}
+		return stmt, nil
 	}
 	return nil, p.Error("Unexpected token (only HTML/tags/filters in templates allowed)", t)
 }
@@ -38,8 +56,12 @@ func (p *Parser) ParseTemplate() (*nodes.Template, error) {
 	p.Template = tpl
 
 	for !p.Stream.End() {
+		start := p.Current()
 		node, err := p.parseDocElement()
 		if err != nil {
+			if p.resync(start, err) {
+				continue
+			}
 			return nil, err
 		}
 		if node != nil {
@@ -4,6 +4,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/noirbizarre/gonja/nodes"
+	"github.com/noirbizarre/gonja/tokens"
 )
 
 func (p *Parser) ParseTest(expr nodes.Expression) (nodes.Expression, error) {
@@ -27,8 +28,13 @@ func (p *Parser) ParseTest(expr nodes.Expression) (nodes.Expression, error) {
 			Kwargs: map[string]nodes.Expression{},
 		}
 
-		arg, err := p.ParseExpression()
-		if err == nil && arg != nil {
+		// Optional argument unless the expression continues
+		if p.Peek(tokens.Name, tokens.String, tokens.Integer, tokens.Float, tokens.Lparen, tokens.Lbracket, tokens.Lbrace) != nil &&
+			p.PeekName("and", "or", "if", "else", "in", "is", "not") == nil {
+			arg, err := p.ParseVariableOrLiteral()
+			if err != nil {
+				return nil, err
+			}
 			test.Args = append(test.Args, arg)
 		}
 
@@ -246,7 +246,16 @@ func (p *Parser) ParseVariable() (nodes.Expression, error) {
 		return br, nil
 	}
 
-	var variable nodes.Node = &nodes.Name{t}
+	return p.parsePostfix(&nodes.Name{t})
+}
+
+// parsePostfix parses the attributes, subscripts and calls following a primary expression
+func (p *Parser) parsePostfix(node nodes.Node) (nodes.Expression, error) {
+	log.WithFields(log.Fields{
+		"current": p.Current(),
+	}).Trace("parsePostfix")
+
+	variable := node
 
 	for !p.Stream.EOF() {
 		if dot := p.Match(tokens.Dot); dot != nil {
@@ -255,6 +264,9 @@ func (p *Parser) ParseVariable() (nodes.Expression, error) {
 				Node:     variable,
 			}
 			tok := p.Match(tokens.Name, tokens.Integer)
+			if tok == nil {
+				return nil, p.Error("This token is not allowed within a variable name.", p.Current())
+			}
 			switch tok.Type {
 			case tokens.Name:
 				getattr.Attr = tok.Val
@@ -264,33 +276,15 @@ func (p *Parser) ParseVariable() (nodes.Expression, error) {
 					return nil, p.Error(err.Error(), tok)
 				}
 				getattr.Index = i
-			default:
-				return nil, p.Error("This token is not allowed within a variable name.", p.Current())
 			}
 			variable = getattr
 			continue
 		} else if bracket := p.Match(tokens.Lbracket); bracket != nil {
-			getitem := &nodes.Getitem{
-				Location: dot,
-				Node:     variable,
-			}
-			tok := p.Match(tokens.String, tokens.Integer)
-			switch tok.Type {
-			case tokens.String:
-				getitem.Arg = tok.Val
-			case tokens.Integer:
-				i, err := strconv.Atoi(tok.Val)
-				if err != nil {
-					return nil, p.Error(err.Error(), tok)
-				}
-				getitem.Index = i
-			default:
-				return nil, p.Error("This token is not allowed within a variable name.", p.Current())
-			}
-			variable = getitem
-			if p.Match(tokens.Rbracket) == nil {
-				return nil, p.Error("Unbalanced bracket", bracket)
+			subscript, err := p.parseSubscript(bracket, variable)
+			if err != nil {
+				return nil, err
 			}
+			variable = subscript
 			continue
 
 		} else if lparen := p.Match(tokens.Lparen); lparen != nil {
@@ -346,15 +340,17 @@ func (p *Parser) ParseVariableOrLiteral() (nodes.Expression, error) {
 	}
 
 	// Is first part a number or a string, there's nothing to resolve (because there's only to return the value then)
+	var primary nodes.Expression
+	var err error
 	switch t.Type {
 	case tokens.Integer, tokens.Float:
-		return p.parseNumber()
+		primary, err = p.parseNumber()
 
 	case tokens.String:
-		return p.parseString()
+		primary, err = p.parseString()
 
 	case tokens.Lparen, tokens.Lbrace, tokens.Lbracket:
-		return p.parseCollection()
+		primary, err = p.parseCollection()
 
 	case tokens.Name:
 		return p.ParseVariable()
@@ -362,4 +358,88 @@ func (p *Parser) ParseVariableOrLiteral() (nodes.Expression, error) {
 	default:
 		return nil, p.Error("Expected either a number, string, keyword or identifier.", t)
 	}
+	if err != nil {
+		return nil, err
+	}
+	// Literals and collections can be subscripted too: "abc"[1:], [1, 2, 3][-1]
+	return p.parsePostfix(primary)
+}
+
+// parseSubscript parses the item or the slice following bracket: [key] or [start:stop:step]
+func (p *Parser) parseSubscript(bracket *tokens.Token, node nodes.Node) (nodes.Expression, error) {
+	log.WithFields(log.Fields{
+		"current": p.Current(),
+	}).Trace("parseSubscript")
+
+	if p.Peek(tokens.String, tokens.Integer) != nil && p.Stream.Peek().Type == tokens.Rbracket {
+		getitem := &nodes.Getitem{
+			Location: bracket,
+			Node:     node,
+		}
+		tok := p.Match(tokens.String, tokens.Integer)
+		switch tok.Type {
+		case tokens.String:
+			getitem.Arg = tok.Val
+		case tokens.Integer:
+			i, err := strconv.Atoi(tok.Val)
+			if err != nil {
+				return nil, p.Error(err.Error(), tok)
+			}
+			getitem.Index = i
+		}
+		p.Match(tokens.Rbracket)
+		return getitem, nil
+	}
+
+	var start nodes.Expression
+	if p.Peek(tokens.Colon) == nil {
+		key, err := p.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.Match(tokens.Rbracket) != nil {
+			return &nodes.Getitem{
+				Location: bracket,
+				Node:     node,
+				Key:      key,
+			}, nil
+		}
+		start = key
+	}
+	return p.parseSlice(bracket, node, start)
+}
+
+// parseSlice parses the slice bounds following start: [start:stop:step]
+func (p *Parser) parseSlice(bracket *tokens.Token, node nodes.Node, start nodes.Expression) (nodes.Expression, error) {
+	log.WithFields(log.Fields{
+		"current": p.Current(),
+	}).Trace("parseSlice")
+
+	slice := &nodes.Slice{
+		Location: bracket,
+		Node:     node,
+		Start:    start,
+	}
+	if p.Match(tokens.Colon) == nil {
+		return nil, p.Error(`Expected ":" or "]"`, p.Current())
+	}
+	bounds := []*nodes.Expression{&slice.Stop, &slice.Step}
+	for idx, bound := range bounds {
+		if idx > 0 && p.Match(tokens.Colon) == nil {
+			break
+		}
+		if p.Peek(tokens.Colon, tokens.Rbracket) != nil {
+			continue
+		}
+		expr, err := p.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		*bound = expr
+	}
+
+	if p.Match(tokens.Rbracket) == nil {
+		return nil, p.Error("Unbalanced bracket", bracket)
+	}
+	return slice, nil
 }
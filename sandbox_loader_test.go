@@ -0,0 +1,181 @@
+package gonja_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+// sandboxTree creates the following tree:
+//
+//	root/
+//	  index.tpl
+//	  escape.tpl -> ../secret.tpl
+//	  shared -> ../shared
+//	shared/
+//	  header.tpl
+//	public/
+//	  page.html
+//	  page.txt
+//	secret.tpl
+func sandboxTree(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gonja-sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"root/index.tpl":    "index",
+		"shared/header.tpl": "header",
+		"public/page.html":  "page",
+		"public/page.txt":   "text",
+		"secret.tpl":        "secret",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret.tpl"), filepath.Join(dir, "root", "escape.tpl")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "shared"), filepath.Join(dir, "root", "shared")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSandboxedFilesystemLoader(t *testing.T) {
+	dir := sandboxTree(t)
+	defer os.RemoveAll(dir)
+
+	loader, err := loaders.NewSandboxedFilesystemLoader(
+		filepath.Join(dir, "root"),
+		"../shared",
+		filepath.Join(dir, "public", "*.html"),
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	for _, tc := range []struct {
+		name     string
+		expected string
+		denied   bool
+	}{
+		{"index.tpl", "index", false},
+		{"./index.tpl", "index", false},
+		{"shared/header.tpl", "header", false},
+		{"../shared/header.tpl", "header", false},
+		{"../public/page.html", "page", false},
+		{filepath.Join(dir, "public", "page.html"), "page", false},
+		{"../public/page.txt", "", true},
+		{"../secret.tpl", "", true},
+		{"shared/../../secret.tpl", "", true},
+		{filepath.Join(dir, "secret.tpl"), "", true},
+		{"escape.tpl", "", true},
+		{"../unknown.tpl", "", true},
+	} {
+		test := tc
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			reader, err := loader.Get(test.name)
+			if test.denied {
+				assert.True(loaders.IsAccessDenied(err), "expected an access denied error, got %v", err)
+				return
+			}
+			if assert.Nil(err) {
+				content, _ := ioutil.ReadAll(reader)
+				assert.Equal(test.expected, string(content))
+			}
+		})
+	}
+}
+
+func TestSandboxedFilesystemLoaderMissingTemplate(t *testing.T) {
+	dir := sandboxTree(t)
+	defer os.RemoveAll(dir)
+
+	loader, err := loaders.NewSandboxedFilesystemLoader(filepath.Join(dir, "root"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = loader.Get("missing.tpl")
+	assert.True(t, os.IsNotExist(err))
+	assert.False(t, loaders.IsAccessDenied(err))
+}
+
+func TestSandboxedFilesystemLoaderInvalidPattern(t *testing.T) {
+	_, err := loaders.NewSandboxedFilesystemLoader("", "[-]")
+	assert.NotNil(t, err)
+}
+
+func TestSandboxedEnvironmentStatements(t *testing.T) {
+	dir := sandboxTree(t)
+	defer os.RemoveAll(dir)
+
+	loader, err := loaders.NewSandboxedFilesystemLoader(filepath.Join(dir, "root"), "../shared")
+	if !assert.Nil(t, err) {
+		return
+	}
+	env := tu.NewEnv(loader)
+
+	for _, tc := range []struct {
+		name     string
+		source   string
+		expected string
+		denied   bool
+	}{
+		{"include", `{% include "shared/header.tpl" %}`, "header", false},
+		{"include outside", `{% include "../secret.tpl" %}`, "", true},
+		{"include symlink escape", `{% include "escape.tpl" %}`, "", true},
+		{"extends outside", `{% extends "../secret.tpl" %}`, "", true},
+		{"import outside", `{% import "../secret.tpl" as secret %}`, "", true},
+		{"from import outside", `{% from "../secret.tpl" import secret %}`, "", true},
+	} {
+		test := tc
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			tpl, err := env.FromString(test.source)
+			if err == nil {
+				var out string
+				out, err = tpl.Execute(nil)
+				if !test.denied && assert.Nil(err) {
+					assert.Equal(test.expected, out)
+				}
+			}
+			if test.denied {
+				if assert.NotNil(err) {
+					assert.True(loaders.IsAccessDenied(err), "expected an access denied error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestSandboxedFilesystemLoaderListTemplates(t *testing.T) {
+	dir := sandboxTree(t)
+	defer os.RemoveAll(dir)
+
+	unrestricted := loaders.MustNewFileSystemLoader(filepath.Join(dir, "root"))
+	names, err := unrestricted.ListTemplates()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"escape.tpl", "index.tpl"}, names)
+
+	loader, err := loaders.NewSandboxedFilesystemLoader(filepath.Join(dir, "root"), "../shared")
+	if !assert.Nil(t, err) {
+		return
+	}
+	names, err = loader.ListTemplates()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"index.tpl"}, names)
+}
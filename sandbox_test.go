@@ -0,0 +1,197 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/config"
+	"github.com/noirbizarre/gonja/exec"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+type sandboxUser struct {
+	Name     string
+	Password string
+	secret   string
+}
+
+func (u *sandboxUser) Greet() string {
+	return "Hello " + u.Name
+}
+
+func (u *sandboxUser) Delete() string {
+	return "deleted"
+}
+
+type sandboxAdmin struct {
+	Name string
+}
+
+func sandboxCtx() map[string]interface{} {
+	user := &sandboxUser{Name: "john", Password: "s3cr3t", secret: "hidden"}
+	return map[string]interface{}{
+		"user":  user,
+		"users": []*sandboxUser{user},
+		"admin": &sandboxAdmin{Name: "root"},
+		"data":  map[string]string{"Password": "not a field"},
+	}
+}
+
+var sandboxCases = []struct {
+	name     string
+	source   string
+	expected string
+	position string
+}{
+	{"allowed field", "{{ user.Name }}", "john", ""},
+	{"allowed method", "{{ user.Greet() }}", "Hello john", ""},
+	{"map keys are not fields", "{{ data.Password }}", "not a field", ""},
+	{"gonja types are trusted", "{% for i in [1, 2] %}{{ loop.Cycle('a', 'b') }}{% endfor %}", "ab", ""},
+	{"allowed filter", "{{ user.Name|lower }}", "john", ""},
+	{"allowed statement", "{% if user %}yes{% endif %}", "yes", ""},
+	{"denied field", "{{ user.Password }}", "", "Line: 1 Col: 8"},
+	{"denied field as item", "{{ user['Password'] }}", "", "Line: 1 Col: 8"},
+	{"unexported field", "{{ user.secret }}", "", "Line: 1 Col: 8"},
+	{"denied method", "{{ user.Delete() }}", "", "Line: 1 Col: 8"},
+	{"denied type", "\n{{ admin.Name }}", "", "Line: 2 Col: 9"},
+	{"denied filter", "{{ user.Name|upper }}", "", "Line: 1 Col: 14"},
+	{"denied filter statement", "{% filter upper %}john{% endfilter %}", "", "Line: 1 Col: 11"},
+	{"denied statement", "{% set x = 1 %}", "", "Line: 1 Col: 1"},
+	{"denied field through filter", "{{ users|selectattr('Password')|list }}", "", ""},
+	{"denied field through attr filter", "{{ user|attr('Password') }}", "", ""},
+	{"denied field through map filter", "{{ users|map(attribute='Password')|list }}", "", ""},
+	{"denied field through sum filter", "{{ users|sum(attribute='Password') }}", "", ""},
+	{"denied field through groupby filter", "{{ users|groupby('Password') }}", "", ""},
+	{"denied field through max filter", "{{ users|max(attribute='Password') }}", "", ""},
+	{"denied field through min filter", "{{ users|min(attribute='Password') }}", "", ""},
+	{"denied field through unique filter", "{{ users|unique(attribute='Password')|list }}", "", ""},
+	{"denied field through tojson filter", "{{ user|tojson }}", "", ""},
+	{"denied nested field through tojson filter", "{{ {'users': users}|tojson }}", "", ""},
+	{"denied type through tojson filter", "{{ [admin]|tojson }}", "", ""},
+	{"denied field through pprint filter", "{{ users|pprint }}", "", ""},
+	{"allowed tojson filter", "{{ data|tojson }}", `{"Password":"not a field"}`, ""},
+}
+
+func TestSandboxedEnvironment(t *testing.T) {
+	policy := exec.NewSandboxPolicy().
+		DenyTypes(sandboxAdmin{}).
+		DenyFields(sandboxUser{}, "Password").
+		AllowMethods(sandboxUser{}, "Greet").
+		DenyFilters("upper").
+		DenyStatements("set")
+	env := gonja.NewSandboxedEnvironment(config.DefaultConfig, gonja.DefaultLoader, policy)
+	for _, sc := range sandboxCases {
+		test := sc
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			out, err := tu.Render(t, env, test.source, sandboxCtx())
+			if test.expected != "" {
+				if assert.Nil(err) {
+					assert.Equal(test.expected, out)
+				}
+				return
+			}
+			if !assert.NotNil(err) {
+				return
+			}
+			assert.True(exec.IsSecurityError(err), "expected a security error, got %v", err)
+			if test.position != "" {
+				assert.Contains(errors.Cause(err).Error(), test.position)
+			}
+		})
+	}
+}
+
+func TestUnsandboxedEnvironment(t *testing.T) {
+	out, err := tu.Render(t, tu.NewEnv(nil), "{{ user.Password }}|{{ user.Delete() }}|{{ 'a'|upper }}", sandboxCtx())
+	if assert.Nil(t, err) {
+		assert.Equal(t, "s3cr3t|deleted|A", out)
+	}
+}
+
+var sandboxSetCases = []struct {
+	name     string
+	source   string
+	expected string
+}{
+	{"namespace attribute", "{% set ns = namespace(count=1) %}{% set ns.count = 2 %}{{ ns.count }}", "2"},
+	{"map item", "{% set data['key'] = 'value' %}{{ data.key }}", "value"},
+	{"dict attribute", "{% set d = {'a': 1} %}{% set d.a = 2 %}{{ d.a }}", "2"},
+	{"denied field", "{% set user.Password = 'x' %}", ""},
+	{"struct field", "{% set user.Name = 'x' %}", ""},
+	{"denied type", "{% set admin.Name = 'pwned' %}", ""},
+	{"unpacking into a struct field", "{% set a, user.Name = 1, 'x' %}", ""},
+}
+
+func TestSandboxedSet(t *testing.T) {
+	policy := exec.NewSandboxPolicy().
+		DenyTypes(sandboxAdmin{}).
+		DenyFields(sandboxUser{}, "Password")
+	env := gonja.NewSandboxedEnvironment(config.DefaultConfig, gonja.DefaultLoader, policy)
+	for _, sc := range sandboxSetCases {
+		test := sc
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx := sandboxCtx()
+			out, err := tu.Render(t, env, test.source, ctx)
+			if test.expected != "" {
+				if assert.Nil(err) {
+					assert.Equal(test.expected, out)
+				}
+				return
+			}
+			if assert.NotNil(err) {
+				assert.True(exec.IsSecurityError(err), "expected a security error, got %v", err)
+			}
+			assert.Equal(&sandboxUser{Name: "john", Password: "s3cr3t", secret: "hidden"}, ctx["user"])
+			assert.Equal(&sandboxAdmin{Name: "root"}, ctx["admin"])
+		})
+	}
+}
+
+// sandboxStringer counts its String calls
+type sandboxStringer struct {
+	calls *int
+}
+
+func (s sandboxStringer) String() string {
+	*s.calls++
+	return "called"
+}
+
+func TestSandboxedStringer(t *testing.T) {
+	for _, source := range []string{
+		"{{ value }}",
+		"{{ [value] }}",
+		"{{ {'key': value} }}",
+		"{{ value ~ '' }}",
+		"{{ value|string }}",
+		"{{ [value, value]|sort }}",
+		"{{ {'a': value, 'b': value}|dictsort(by='value') }}",
+	} {
+		src := source
+		t.Run(src, func(t *testing.T) {
+			assert := assert.New(t)
+			calls := 0
+			ctx := map[string]interface{}{"value": sandboxStringer{calls: &calls}}
+
+			policy := exec.NewSandboxPolicy()
+			tpl, err := gonja.NewSandboxedEnvironment(config.DefaultConfig, gonja.DefaultLoader, policy).FromString(src)
+			if !assert.Nil(err) {
+				return
+			}
+			_, err = tpl.Execute(ctx)
+			if assert.NotNil(err) {
+				assert.True(exec.IsSecurityError(err), "expected a security error, got %v", err)
+			}
+			assert.Equal(0, calls, "String should not have been called")
+
+			policy.AllowMethods(sandboxStringer{}, "String")
+			_, err = tpl.Execute(ctx)
+			assert.Nil(err)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+var setErrorCases = []struct {
+	name     string
+	source   string
+	expected string
+}{
+	{"too many values", "{% set a, b = [1, 2, 3] %}", "Unable to unpack 3 values into 2 targets"},
+	{"not enough values", "{% set a, b, c = 'xy' %}", "Unable to unpack 2 values into 3 targets"},
+	{"not iterable", "{% set a, b = 42 %}", "Unable to unpack 42: int is not iterable"},
+	{"wrong field type", "{% set obj.Name = [1] %}", `Can't write field "Name": exec.ValuesList is not assignable to string`},
+}
+
+func TestSetErrors(t *testing.T) {
+	type object struct{ Name string }
+	env := tu.NewEnv(nil)
+	for _, sc := range setErrorCases {
+		test := sc
+		t.Run(test.name, func(t *testing.T) {
+			_, err := tu.Render(t, env, test.source, map[string]interface{}{"obj": &object{}})
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), test.expected)
+			}
+		})
+	}
+}
+
+var setParseErrorCases = []struct {
+	name     string
+	source   string
+	expected string
+}{
+	{"call target", "{% set obj.Name() = 1 %}", "Unexpected set target"},
+	{"tuple without expression", "{% set a, b %}{% endset %}", "Expected '='."},
+	{"endset with arguments", "{% set a %}value{% endset a %}", "Arguments not allowed here."},
+}
+
+func TestSetParseErrors(t *testing.T) {
+	for _, sc := range setParseErrorCases {
+		test := sc
+		t.Run(test.name, func(t *testing.T) {
+			_, err := gonja.FromString(test.source)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), test.expected)
+			}
+		})
+	}
+}
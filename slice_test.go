@@ -0,0 +1,33 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+var sliceErrorCases = []struct {
+	name     string
+	source   string
+	expected string
+}{
+	{"not sliceable", "{{ value[1:] }}", "Unable to slice Name(Val=value Line=1 Col=4): int is not sliceable"},
+	{"not an integer", "{{ items[:'a'] }}", "Slice indices must be integers, got a"},
+	{"zero step", "{{ items[::0] }}", "Slice step cannot be zero"},
+}
+
+func TestSliceErrors(t *testing.T) {
+	env := tu.NewEnv(nil)
+	ctx := map[string]interface{}{"value": 42, "items": []int{1, 2, 3}}
+	for _, sc := range sliceErrorCases {
+		test := sc
+		t.Run(test.name, func(t *testing.T) {
+			_, err := tu.Render(t, env, test.source, ctx)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), test.expected)
+			}
+		})
+	}
+}
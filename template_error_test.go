@@ -0,0 +1,161 @@
+package gonja_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+	"github.com/noirbizarre/gonja/loaders"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+var templateErrorCases = []struct {
+	name    string
+	source  string
+	phase   exec.Phase
+	line    int
+	column  int
+	message string
+}{
+	{"unterminated string", "hello\n{{ 'abc }}", exec.LexPhase, 2, 4, "Unterminated string"},
+	{"unknown statement", "<ul>\n  {% endfo %}\n</ul>", exec.ParsePhase, 2, 6, "Statement 'endfo' not found (or beginning not provided)"},
+	{"incomplete expression", "{{ 1 + }}", exec.ParsePhase, 1, 8, "Expected either a number, string, keyword or identifier."},
+	{"bad macro signature", "<p>\n  {% macro hello(1) %}{% endmacro %}", exec.ParsePhase, 2, 3, "Expected argument name as identifier."},
+}
+
+func TestTemplateErrorOnCompile(t *testing.T) {
+	for _, tc := range templateErrorCases {
+		test := tc
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			_, err := gonja.FromString(test.source)
+			te, ok := exec.AsTemplateError(err)
+			if !assert.True(ok, "expected a TemplateError, got %v", err) {
+				return
+			}
+			assert.Equal("string", te.Name)
+			assert.Equal(test.phase, te.Phase)
+			assert.Equal(test.line, te.Line)
+			assert.Equal(test.column, te.Column)
+			assert.Equal(test.message, te.Message)
+			assert.Equal(test.source, te.Source)
+		})
+	}
+}
+
+func TestTemplateErrorOnMissingDependency(t *testing.T) {
+	for _, source := range []string{
+		`{% include "missing.tpl" %}`,
+		`{% import "missing.tpl" as macros %}`,
+		`{% from "missing.tpl" import macro %}`,
+	} {
+		src := source
+		t.Run(src, func(t *testing.T) {
+			assert := assert.New(t)
+			_, err := gonja.FromString("<p>\n  " + src)
+			te, ok := exec.AsTemplateError(err)
+			if !assert.True(ok, "expected a TemplateError, got %v", err) {
+				return
+			}
+			assert.Equal(exec.ParsePhase, te.Phase)
+			assert.Equal(2, te.Line)
+			assert.Equal(3, te.Column)
+			assert.Contains(te.Message, `Unable to parse template "missing.tpl"`)
+		})
+	}
+}
+
+func TestTemplateErrorExcerpt(t *testing.T) {
+	_, err := gonja.FromString("<ul>\n  {% endfo %}\n</ul>\n")
+	expected := `parse error in template 'string' (Line: 2 Col: 6, near "endfo"): Statement 'endfo' not found (or beginning not provided)
+  1 | <ul>
+> 2 |   {% endfo %}
+    |      ^^^^^
+  3 | </ul>`
+	assert.Equal(t, expected, fmt.Sprintf("%+v", err))
+}
+
+func TestTemplateErrorOnRender(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	failure := errors.New("failure")
+	env.Globals.Set("fail", func() (string, error) { return "", failure })
+
+	tpl, err := env.FromFile("testData/template_errors/page.tpl")
+	if !assert.Nil(err) {
+		return
+	}
+	_, err = tpl.Execute(nil)
+	te, ok := exec.AsTemplateError(err)
+	if !assert.True(ok, "expected a TemplateError, got %v", err) {
+		return
+	}
+	assert.Equal(failure, errors.Cause(err))
+	assert.Equal(exec.RenderPhase, te.Phase)
+	assert.Equal("testData/template_errors/macros.tpl", te.Name)
+	assert.Equal(2, te.Line)
+	assert.Equal(16, te.Column)
+	if assert.Len(te.Stack, 2) {
+		assert.Equal("include", te.Stack[0].Kind)
+		assert.Equal("testData/template_errors/partial.tpl", te.Stack[0].Name)
+		assert.Equal("testData/template_errors/page.tpl", te.Stack[0].Template)
+		assert.Equal(2, te.Stack[0].Token.Line)
+		assert.Equal("macro", te.Stack[1].Kind)
+		assert.Equal("broken", te.Stack[1].Name)
+		assert.Equal("testData/template_errors/partial.tpl", te.Stack[1].Template)
+	}
+	assert.Contains(fmt.Sprintf("%+v", err), `
+> 2 |   <span>{{ fail() }}</span>
+    |                ^
+  3 | {% endmacro %}
+  from macro "broken" in template 'testData/template_errors/partial.tpl'
+  from include "testData/template_errors/partial.tpl" (Line: 2 Col: 53) in template 'testData/template_errors/page.tpl'`)
+}
+
+func TestTemplateErrorKeepsCause(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Limits = exec.Limits{MaxLoopIterations: 3}
+	_, err := tu.Render(t, env, "{% for i in range(10) %}{{ i }}{% endfor %}", nil)
+	assert.IsType(&exec.TemplateError{}, err)
+	assert.IsType(&exec.LoopLimitError{}, errors.Cause(err))
+}
+
+func TestTemplateErrorSourceOfDependencies(t *testing.T) {
+	assert := assert.New(t)
+	loader := loaders.NewMapLoader(map[string]string{
+		"page.html":    `{% include "partial.html" %}`,
+		"partial.html": "<p>\n{{ fail() }}",
+		"dynamic.html": "<b>\n{{ fail() }}",
+	})
+	env := tu.NewEnv(loader)
+	env.AutoReload = true
+	env.Globals.Set("fail", func() (string, error) { return "", errors.New("failure") })
+
+	tpl, err := env.FromFile("page.html")
+	if !assert.Nil(err) {
+		return
+	}
+	// The reported source is the one the template has been compiled with
+	loader.Set("partial.html", "changed")
+	_, err = tpl.Execute(nil)
+	if te, ok := exec.AsTemplateError(err); assert.True(ok, "expected a TemplateError, got %v", err) {
+		assert.Equal("partial.html", te.Name)
+		assert.Equal("<p>\n{{ fail() }}", te.Source)
+	}
+
+	// Templates included dynamically are reported too
+	tpl, err = env.FromString(`{% include name %}`)
+	if !assert.Nil(err) {
+		return
+	}
+	_, err = tpl.Execute(map[string]interface{}{"name": "dynamic.html"})
+	if te, ok := exec.AsTemplateError(err); assert.True(ok, "expected a TemplateError, got %v", err) {
+		assert.Equal("dynamic.html", te.Name)
+		assert.Equal("<b>\n{{ fail() }}", te.Source)
+	}
+}
@@ -0,0 +1 @@
+{{ value }}
\ No newline at end of file
@@ -0,0 +1,10 @@
+\BLOCK{ set title = 'Hello' }\BLOCK{ set items = [1, 2] -}
+\section{\VAR{ title }}
+\VAR{ {'a': title}['a'] }
+\BLOCK{ for i in items }\item \VAR{ i } \BLOCK{ endfor }
+\BLOCK{ for i in items -}
+  \VAR{ i }
+\BLOCK{- endfor }
+a\#{ \BLOCK{ if \VAR{ title }b
+\BLOCK{ raw }\VAR{ title }\BLOCK{ endraw }
+{{ title }}{% if %}{# #}
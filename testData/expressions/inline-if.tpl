@@ -0,0 +1,13 @@
+{{ 'yes' if true else 'no' }}
+{{ 'yes' if false else 'no' }}
+{{ 'yes' if simple.number > 10 else 'no' }}
+[{{ 'yes' if false }}]
+{{ 'a' if false else 'b' if false else 'c' }}
+{{ 'defined' if simple.number is defined and simple.bool_true else 'undefined' }}
+{{ ('yes' if true else 'no')|upper }}
+{{ 'yes' if false else 'no'|upper }}
+{{ simple.missing|default('fallback' if true else 'nothing') }}
+{% set value = 1 if simple.bool_false else 2 %}{{ value }}
+{% macro greet(name='world' if true else 'nobody') %}Hello {{ name }}!{% endmacro %}{{ greet() }}
+{% for item in simple.multiple_item_list if item > 5 %}{{ item }}{% endfor %}
+{{ [1 if true else 0, 2] }}
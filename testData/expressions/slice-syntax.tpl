@@ -0,0 +1,22 @@
+{{ simple.multiple_item_list[1:3] }}
+{{ simple.multiple_item_list[:3] }}
+{{ simple.multiple_item_list[7:] }}
+{{ simple.multiple_item_list[:] }}
+{{ simple.multiple_item_list[::3] }}
+{{ simple.multiple_item_list[::-1] }}
+{{ simple.multiple_item_list[-3:] }}
+{{ simple.multiple_item_list[:-8] }}
+{{ simple.multiple_item_list[8:2:-2] }}
+{{ simple.multiple_item_list[2:100] }}
+{{ simple.multiple_item_list[5:2] }}
+{{ simple.multiple_item_list[number - 10:number - 8] }}
+{{ simple.name[:4] }}
+{{ simple.name[::-1] }}
+{{ simple.chinese_hello_world[1:3] }}
+{% set tuple = (1, 2, 3, 4) %}{{ tuple[1::2] }}
+{{ simple.name[:4]|upper }}
+{% for item in simple.multiple_item_list[-2:] %}{{ item }};{% endfor %}
+{{ "abc"[1:] }}
+{{ [1, 2, 3][1:] }}
+{{ (simple.multiple_item_list)[1:3] }}
+{{ simple.multiple_item_list[number - 10:] | length }}
@@ -0,0 +1,12 @@
+{{ simple.multiple_item_list[-1] }}
+{{ simple.multiple_item_list[number - 5] }}
+{% set index = 2 %}{{ simple.multiple_item_list[index] }}
+{% set key = "abc" %}{{ simple.strmap[key] }}
+{{ simple.strmap["a" ~ "bc"] }}
+{{ simple["multiple_item_list"][-2] }}
+{{ [1, 2, 3][-1] }}
+{{ "abc"[0] }}
+{{ {"a": 1}["a"] }}
+{{ (simple.misc_list)[0] }}
+{{ simple.multiple_item_list[100] }}
+{% set ns = namespace(items={}) %}{% set ns.items[key] = "ok" %}{{ ns.items.abc }}
@@ -0,0 +1 @@
+{% include included %}
\ No newline at end of file
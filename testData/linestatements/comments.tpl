@@ -0,0 +1,8 @@
+a ## comment
+b
+## comment
+c
+# if true ## comment
+yes
+# endif
+end
@@ -0,0 +1,3 @@
+# if true
+yes
+# endif
\ No newline at end of file
@@ -0,0 +1,5 @@
+# raw
+# if
+{{ i }}
+# endraw
+end
@@ -0,0 +1,22 @@
+# set items = [1, 2]
+# for i in items
+{{ i }}
+# endfor
+end
+<ul>
+  # for i in items
+  <li>{{ i }}</li>
+  # endfor
+</ul>
+# for i in items:
+{{ i }}
+# endfor
+# for i in [
+  1, 2
+]
+{{ i }}
+# endfor
+# if true
+{% for i in items %}{{ i }}{% endfor %}.
+# endif
+a # b
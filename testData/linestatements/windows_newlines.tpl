@@ -0,0 +1,4 @@
+# for i in [1, 2]
+{{ i }}
+# endfor
+end
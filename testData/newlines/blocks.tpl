@@ -0,0 +1,5 @@
+{% if true %}
+a{% endif %}
+b
+  {% if true %}c{% endif %}
+{% if true %}d{% endif %}
@@ -0,0 +1,5 @@
+unix
+windows
+macmixed
+
+end
@@ -0,0 +1 @@
+{{ simple.newline_text }}
@@ -8,3 +8,12 @@
 {% autoescape false %}
 {{ "<script>alert('xss');</script>"|escape }}
 {% endautoescape %}
+{% set value = "<a href=\"x\">Tom & 'Jerry'</a>" -%}
+{% autoescape "html" %}{{ value }}{% endautoescape %}
+{% autoescape "xml" %}{{ value }}{% endautoescape %}
+{% autoescape "js" %}{{ value }}{% endautoescape %}
+{% autoescape "css" %}{{ value }}{% endautoescape %}
+{% autoescape "url" %}{{ value }}{% endautoescape %}
+{% autoescape "json" %}{{ value }}{% endautoescape %}
+{% set value = "a&b" -%}
+{{ value }}|{% autoescape "url" %}{{ value }}|{{ value|safe }}{% endautoescape %}|{{ value }}
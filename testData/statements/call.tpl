@@ -0,0 +1,13 @@
+{% macro card(title) -%}
+<div class="card"><h1>{{ title }}</h1>{{ caller() }}</div>
+{%- endmacro %}
+{% macro list(items) -%}
+<ul>{% for item in items %}<li>{{ caller(item) }}</li>{% endfor %}</ul>
+{%- endmacro %}
+{% import "call.helper" as helpers -%}
+{% from "call.helper" import panel -%}
+{% set greeting = "Hello" -%}
+{% call card("Title") %}{{ greeting }} from the caller{% endcall %}
+{% call(item) list([1, 2, 3]) %}{{ greeting }} {{ item }}{% endcall %}
+{% call helpers.panel(kind="info") %}Imported{% endcall %}
+{% call panel() %}<b>{{ greeting }}</b>{% endcall %}
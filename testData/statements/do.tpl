@@ -0,0 +1,2 @@
+[{% do simple.name|upper %}]
+{% for item in simple.misc_list %}{% do item %}{% endfor %}done
@@ -0,0 +1,16 @@
+{% set tree = [
+    {"name": "root", "children": [
+        {"name": "a", "children": [{"name": "a1", "children": []}, {"name": "a2", "children": []}]},
+        {"name": "b", "children": []},
+    ]},
+    {"name": "other", "children": []},
+] -%}
+<ul>
+{%- for item in tree recursive %}
+  <li>{{ item.name }} ({{ loop.depth }}/{{ loop.depth0 }} {{ loop.index }}/{{ loop.length }})
+  {%- if item.children -%}
+  <ul>{{ loop(item.children) }}</ul>
+  {%- endif %}</li>
+{%- endfor %}
+</ul>
+{% for item in tree if item.children recursive %}{{ item.name }}{% if loop.last %}!{% endif %}[{{ loop(item.children) }}]{% else %}-{% endfor %}
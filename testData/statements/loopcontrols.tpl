@@ -0,0 +1,7 @@
+{% for i in range(10) %}{% if i == 3 %}{% break %}{% endif %}{{ i }}{% endfor %}
+{% for i in range(6) %}{% if i is odd %}{% continue %}{% endif %}{{ i }}{% endfor %}
+{% for i in range(5) %}{% with skip = i < 2 %}{% if skip %}{% continue %}{% endif %}{% endwith %}{{ loop.index }}/{{ loop.revindex }}{% if not loop.last %},{% endif %}{% endfor %}
+{% for i in range(5) %}{% filter upper %}{% if i == 2 %}{% break %}{% endif %}i{{ i }}{% endfilter %}{% endfor %}
+{% for row in [[1, 2, 3], [4, 5, 6]] %}{% for col in row %}{% if col is even %}{% break %}{% endif %}{{ col }}{% endfor %};{% endfor %}
+{% for i in range(3) if i > 0 %}{% if loop.last %}{% break %}{% endif %}{{ i }}:{{ loop.length }}{% else %}empty{% endfor %}
+{% for i in range(10) %}{% if i == 5 %}{% break %}{% elif i is even %}{% continue %}{% endif %}{{ i }}{% endfor %}
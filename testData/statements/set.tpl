@@ -4,3 +4,4 @@
 {% set new_var = item %}{{ new_var }}{% endfor %}
 {{ new_var }}
 {% set car={} %}{{ car.Drive }}No Panic
+{% set value = not_found %}{{ value is defined }}
@@ -0,0 +1,8 @@
+{% set greeting %}Hello <b>{{ simple.name }}</b>!{% endset %}{{ greeting }}
+{% set shout | upper %}Hello {{ simple.name }}{% endset %}{{ shout }}
+{% set chained | trim | replace("world", "everyone") %}
+  hello world
+{% endset %}[{{ chained }}]
+{% macro wrap(content) %}<div>{{ content }}</div>{% endmacro %}{% set inner %}<span>{{ simple.str }}</span>{% endset %}{{ wrap(inner) }}
+{% autoescape false %}{% set raw %}<i>raw</i>{% endset %}{% autoescape true %}{{ raw }}{% endautoescape %}{% endautoescape %}
+{% for item in simple.misc_list %}{% set line %}{{ loop.index }}={{ item }}{% endset %}{{ line }};{% endfor %}
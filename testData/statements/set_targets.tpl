@@ -0,0 +1,7 @@
+{% set ns = namespace(count=0, found=false) %}{% for item in simple.multiple_item_list %}{% set ns.count = ns.count + 1 %}{% if item > 20 and not ns.found %}{% set ns.found = item %}{% endif %}{% endfor %}{{ ns.count }} {{ ns.found }}
+{% set a, b = [1, 2] %}{{ a }} {{ b }}
+{% set first, second, third = "xyz" %}{{ third }}{{ second }}{{ first }}
+{% set a, b = b, a %}{{ a }} {{ b }}
+{% set ns.x, ns.y = (3, 4) %}{{ ns.x + ns.y }}
+{% set ns.title %}<b>{{ simple.name }}</b>{% endset %}{{ ns.title }}
+{% set data = {'key': 'value'} %}{% set data.key = 'other' %}{% set data.new = 42 %}{{ data.key }} {{ data.new }}
@@ -0,0 +1,3 @@
+{% macro broken() %}
+  <span>{{ fail() }}</span>
+{% endmacro %}
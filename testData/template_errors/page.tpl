@@ -0,0 +1,2 @@
+<h1>Title</h1>
+{% include "testData/template_errors/partial.tpl" %}
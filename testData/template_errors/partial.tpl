@@ -0,0 +1,4 @@
+{% from "testData/template_errors/macros.tpl" import broken %}
+<div>
+  {{ broken() }}
+</div>
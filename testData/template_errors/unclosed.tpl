@@ -0,0 +1,5 @@
+<ul>
+{% for item in items %}
+  <li>{{ item }}</li>
+{% endfo %}
+</ul>
@@ -5,3 +5,6 @@
 {{ 22 is divisibleby("3") }}
 {{ 85 is divisibleby(simple.number) }}
 {{ 84 is divisibleby(simple.number) }}
+{{ 21 is divisibleby 3 and 22 is divisibleby 2 }}
+{{ "yes" if 21 is divisibleby 3 else "no" }}
+{{ 21 is divisibleby simple.number }}
@@ -29,6 +29,38 @@ func TestEnv(root string) *gonja.Environment {
 	return env
 }
 
+// NewEnv creates an Environment with its own default configuration
+// loading the templates with loader, or gonja.DefaultLoader if nil.
+func NewEnv(loader loaders.Loader) *gonja.Environment {
+	if loader == nil {
+		loader = gonja.DefaultLoader
+	}
+	return gonja.NewEnvironment(gonja.NewConfig(), loader)
+}
+
+// Render compiles source with env, failing the test if it is invalid,
+// and renders it with ctx. The output written before an error is returned with it.
+func Render(t *testing.T, env *gonja.Environment, source string, ctx map[string]interface{}) (string, error) {
+	tpl, err := env.FromString(source)
+	if err != nil {
+		t.Fatalf("Error on FromString('%s'):\n%s", source, err.Error())
+	}
+	var out bytes.Buffer
+	err = tpl.ExecuteWriter(ctx, &out)
+	return out.String(), err
+}
+
+// RenderFile is like Render for the named template
+func RenderFile(t *testing.T, env *gonja.Environment, name string, ctx map[string]interface{}) (string, error) {
+	tpl, err := env.FromFile(name)
+	if err != nil {
+		t.Fatalf("Error on FromFile('%s'):\n%s", name, err.Error())
+	}
+	var out bytes.Buffer
+	err = tpl.ExecuteWriter(ctx, &out)
+	return out.String(), err
+}
+
 func GlobTemplateTests(t *testing.T, root string, env *gonja.Environment) {
 	pattern := filepath.Join(root, `*.tpl`)
 	matches, err := filepath.Glob(pattern)
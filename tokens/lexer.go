@@ -4,6 +4,7 @@ import (
 	"fmt"
 	// "encoding/json"
 	"regexp"
+	"sort"
 	// "strconv"
 	"strings"
 	"unicode"
@@ -14,7 +15,8 @@ import (
 
 // EOF is an arbitraty value for End Of File
 const rEOF = -1
-const re_ENDRAW = `%s\s*%s`
+const re_ENDRAW = `%s[-+]?\s*%s`
+const re_LINE_ENDRAW = `(?m)(?:%s[-+]?|^[ \t]*%s)\s*%s`
 
 var escapedStrings = map[string]string{
 	`\"`: `"`,
@@ -41,43 +43,118 @@ type Lexer struct {
 	delimiters    []rune
 	RawStatements rawStmt
 	rawEnd        *regexp.Regexp
+	starts        []tagStart // tag opening delimiters, longest first
+	tag           Type       // kind of the tag being lexed
+	Recover       bool       // Resume lexing at the next tag after an error
 }
 
 // TODO: set from env
 type rawStmt map[string]*regexp.Regexp
 
+// tagStart associates a tag opening delimiter with its lexing state
+type tagStart struct {
+	delimiter string
+	state     lexFn
+}
+
 // NewLexer creates a new scanner for the input string.
 func NewLexer(input string) *Lexer {
-	cfg := config.DefaultConfig
-	return &Lexer{
+	return NewLexerWithConfig(input, config.DefaultConfig)
+}
+
+// NewLexerWithConfig creates a new scanner for the input string
+// using the delimiters defined by the given configuration.
+// If cfg is nil, config.DefaultConfig is used.
+func NewLexerWithConfig(input string, cfg *config.Config) *Lexer {
+	if cfg == nil {
+		cfg = config.DefaultConfig
+	}
+	l := &Lexer{
 		Input:  input,
 		Tokens: make(chan *Token),
 		Config: cfg,
 		RawStatements: rawStmt{
-			"raw":     regexp.MustCompile(fmt.Sprintf(`%s\s*endraw`, cfg.BlockStartString)),
-			"comment": regexp.MustCompile(fmt.Sprintf(`%s\s*endcomment`, cfg.BlockStartString)),
+			"raw":     endRawRegexp(cfg, "endraw"),
+			"comment": endRawRegexp(cfg, "endcomment"),
 		},
 	}
+	l.starts = []tagStart{
+		{cfg.CommentStartString, l.lexComment},
+		{cfg.VariableStartString, l.lexVariable},
+		{cfg.BlockStartString, l.lexBlock},
+		{cfg.LineCommentPrefix, l.lexLineComment},
+	}
+	// Longest delimiters first so that overlapping delimiters
+	// (ie. "<%" and "<%=") are properly distinguished
+	sort.SliceStable(l.starts, func(i, j int) bool {
+		return len(l.starts[i].delimiter) > len(l.starts[j].delimiter)
+	})
+	return l
 }
 
 func Lex(input string) *Stream {
-	l := NewLexer(input)
+	return LexWithConfig(input, config.DefaultConfig)
+}
+
+// LexWithConfig tokenizes the input using the given configuration delimiters
+func LexWithConfig(input string, cfg *config.Config) *Stream {
+	l := NewLexerWithConfig(input, cfg)
 	go l.Run()
 	return NewStream(l.Tokens)
 }
 
+// LexRecover tokenizes the input like Lex but does not stop on errors:
+// error tokens are kept in the stream and lexing resumes at the next tag.
+func LexRecover(input string, cfg *config.Config) *Stream {
+	l := NewLexerWithConfig(input, cfg)
+	l.Recover = true
+	go l.Run()
+	return newStream(ChanIterator(l.Tokens), true)
+}
+
+func endRawRegexp(cfg *config.Config, name string) *regexp.Regexp {
+	start := regexp.QuoteMeta(cfg.BlockStartString)
+	if cfg.LineStatementPrefix != "" {
+		prefix := regexp.QuoteMeta(cfg.LineStatementPrefix)
+		return regexp.MustCompile(fmt.Sprintf(re_LINE_ENDRAW, start, prefix, name))
+	}
+	return regexp.MustCompile(fmt.Sprintf(re_ENDRAW, start, name))
+}
+
 // errorf returns an error token and terminates the scan
 // by passing back a nil pointer that will be the next
 // state, terminating Lexer.Run.
+// In recovery mode, the scan resumes at the next tag instead.
 func (l *Lexer) errorf(format string, args ...interface{}) lexFn {
+	line, col := ReadablePosition(l.Pos, l.Input)
 	l.Tokens <- &Token{
 		Type: Error,
 		Val:  fmt.Sprintf(format, args...),
 		Pos:  l.Pos,
+		Line: line,
+		Col:  col,
+	}
+	if l.Recover {
+		return l.lexRecover
 	}
 	return nil
 }
 
+// lexRecover skips the input up to the next tag opening delimiter
+// (or line start if line statements are enabled) and resumes lexing from there.
+func (l *Lexer) lexRecover() lexFn {
+	l.delimiters = nil
+	l.rawEnd = nil
+	for l.Pos < len(l.Input) && l.tagStartState() == nil {
+		if l.Config.LineStatementPrefix != "" && l.Pos > 0 && l.Input[l.Pos-1] == '\n' {
+			break
+		}
+		l.next()
+	}
+	l.ignore()
+	return l.lexData
+}
+
 // Position return the current position in the input
 func (l *Lexer) Position() *Position {
 	return &Position{
@@ -180,20 +257,28 @@ func (l *Lexer) hasPrefix(prefix string) bool {
 	return strings.HasPrefix(l.Input[l.Pos:], prefix)
 }
 
-func (l *Lexer) popDelimiter(r rune) bool {
+// atLineComment returns true if the input at the current position
+// starts a line comment
+func (l *Lexer) atLineComment() bool {
+	return l.Config.LineCommentPrefix != "" && l.hasPrefix(l.Config.LineCommentPrefix)
+}
+
+// popDelimiter pops the expected closing delimiter r.
+// If r is not the expected one, it returns false along with the error state.
+func (l *Lexer) popDelimiter(r rune) (lexFn, bool) {
 	if len(l.delimiters) == 0 {
-		l.errorf(`Unexpected delimiter "%c"`, r)
-		return false
+		l.backup()
+		return l.errorf(`Unexpected delimiter "%c"`, r), false
 	}
 	last := len(l.delimiters) - 1
 	expected := l.delimiters[last]
 	if r != expected {
-		l.errorf(`Unbalanced delimiters, expected "%c", got "%c"`, expected, r)
-		return false
+		l.backup()
+		return l.errorf(`Unbalanced delimiters, expected "%c", got "%c"`, expected, r), false
 	}
 	// l.delimiters[last] = nil // Erase element (write zero value)
 	l.delimiters = l.delimiters[:last]
-	return true
+	return nil, true
 }
 
 // return whether or not we are expecting r as the next delimiter
@@ -205,27 +290,71 @@ func (l *Lexer) expectDelimiter(r rune) bool {
 	return r == expected
 }
 
-func (l *Lexer) lexData() lexFn {
-	for {
-		if l.hasPrefix(l.Config.CommentStartString) {
-			if l.Pos > l.Start {
-				l.emit(Data)
-			}
-			return l.lexComment
+// tagStartState returns the state matching the tag opening delimiter
+// at the current position or nil if there is none
+func (l *Lexer) tagStartState() lexFn {
+	for _, start := range l.starts {
+		if start.delimiter != "" && l.hasPrefix(start.delimiter) {
+			return start.state
 		}
+	}
+	return nil
+}
 
-		if l.hasPrefix(l.Config.VariableStartString) {
-			if l.Pos > l.Start {
-				l.emit(Data)
-			}
-			return l.lexVariable
+// emitDataUntil emits the pending data up to pos
+// and ignores the input between pos and the current position
+func (l *Lexer) emitDataUntil(pos int) {
+	if pos > l.Start {
+		current := l.Pos
+		l.Pos = pos
+		l.emit(Data)
+		l.Pos = current
+	}
+	l.ignore()
+}
+
+// lineStatementState returns the line statement state if the current line
+// starts with the line statement prefix (ignoring indentation) or nil otherwise.
+func (l *Lexer) lineStatementState() lexFn {
+	prefix := l.Config.LineStatementPrefix
+	if prefix == "" || (l.Pos > 0 && l.Input[l.Pos-1] != '\n') {
+		return nil
+	}
+	pos := l.Pos
+	for pos < len(l.Input) && isSpace(rune(l.Input[pos])) {
+		pos++
+	}
+	remaining := l.Input[pos:]
+	if !strings.HasPrefix(remaining, prefix) {
+		return nil
+	}
+	// Line comment has precedence if it is the longest match
+	comment := l.Config.LineCommentPrefix
+	if comment != "" && len(comment) > len(prefix) && strings.HasPrefix(remaining, comment) {
+		return nil
+	}
+	l.emitDataUntil(l.Pos)
+	l.Pos = pos
+	l.ignore()
+	return l.lexLineStatement
+}
+
+func (l *Lexer) lexData() lexFn {
+	for {
+		if state := l.lineStatementState(); state != nil {
+			return state
 		}
 
-		if l.hasPrefix(l.Config.BlockStartString) {
-			if l.Pos > l.Start {
-				l.emit(Data)
+		if state := l.tagStartState(); state != nil {
+			end := l.Pos
+			if l.atLineComment() {
+				// Line comments strip the preceding whitespaces
+				for end > l.Start && isSpace(rune(l.Input[end-1])) {
+					end--
+				}
 			}
-			return l.lexBlock
+			l.emitDataUntil(end)
+			return state
 		}
 
 		if l.next() == rEOF {
@@ -252,20 +381,28 @@ func (l *Lexer) lexRaw() lexFn {
 	l.Pos += loc[0]
 	l.emit(Data)
 	l.rawEnd = nil
-	return l.lexBlock
-	// regexp.MustCompile(`(?m)(?P<key>\w+):\s+(?P<value>\w+)$`)
-	// idx := pattern
+	// Closing statement is either a block or a line statement
+	return l.lexData
 }
 
 func (l *Lexer) lexComment() lexFn {
 	l.Pos += len(l.Config.CommentStartString)
+	l.accept("-")
 	l.emit(CommentBegin)
 	i := strings.Index(l.Input[l.Pos:], l.Config.CommentEndString)
 	if i < 0 {
 		return l.errorf("unclosed comment")
 	}
 	l.Pos += i
+	// Keep the trim marker with the closing delimiter
+	trim := i > 0 && l.Input[l.Pos-1] == '-'
+	if trim {
+		l.Pos--
+	}
 	l.emit(Data)
+	if trim {
+		l.Pos++
+	}
 	l.Pos += len(l.Config.CommentEndString)
 	l.emit(CommentEnd)
 	return l.lexData
@@ -275,6 +412,7 @@ func (l *Lexer) lexVariable() lexFn {
 	l.Pos += len(l.Config.VariableStartString)
 	l.accept("-")
 	l.emit(VariableBegin)
+	l.tag = VariableBegin
 	return l.lexExpression
 }
 
@@ -289,6 +427,18 @@ func (l *Lexer) lexBlock() lexFn {
 	l.Pos += len(l.Config.BlockStartString)
 	l.accept("+-")
 	l.emit(BlockBegin)
+	l.tag = BlockBegin
+	return l.lexStatementName
+}
+
+func (l *Lexer) lexLineStatement() lexFn {
+	l.Pos += len(l.Config.LineStatementPrefix)
+	l.emit(LinestatementBegin)
+	l.tag = LinestatementBegin
+	return l.lexStatementName
+}
+
+func (l *Lexer) lexStatementName() lexFn {
 	for isSpace(l.peek()) {
 		l.next()
 	}
@@ -304,6 +454,61 @@ func (l *Lexer) lexBlock() lexFn {
 	return l.lexExpression
 }
 
+// lexLineStatementEnd consumes the end of line,
+// ignoring any trailing line comment.
+func (l *Lexer) lexLineStatementEnd() lexFn {
+	if l.atLineComment() {
+		for r := l.peek(); r != rEOF && !isEndOfLine(r); r = l.peek() {
+			l.next()
+		}
+		l.ignore()
+	}
+	if !l.accept("\n") && l.accept("\r") {
+		l.accept("\n")
+	}
+	l.emit(LinestatementEnd)
+	if l.rawEnd != nil {
+		return l.lexRaw
+	}
+	return l.lexData
+}
+
+func (l *Lexer) lexLineComment() lexFn {
+	l.Pos += len(l.Config.LineCommentPrefix)
+	l.emit(LinecommentBegin)
+	for r := l.peek(); r != rEOF && !isEndOfLine(r); r = l.peek() {
+		l.next()
+	}
+	l.emit(Linecomment)
+	l.emit(LinecommentEnd)
+	return l.lexData
+}
+
+// tagEndState returns the state matching the current tag closing delimiter
+// at the current position or nil if there is none
+func (l *Lexer) tagEndState() lexFn {
+	switch l.tag {
+	case VariableBegin:
+		if l.hasPrefix(l.Config.VariableEndString) {
+			return l.lexVariableEnd
+		}
+	case BlockBegin:
+		if l.hasPrefix(l.Config.BlockEndString) {
+			return l.lexBlockEnd
+		}
+	case LinestatementBegin:
+		// Line statements may span multiple lines while delimiters are open
+		if len(l.delimiters) > 0 {
+			return nil
+		}
+		r := l.peek()
+		if r == rEOF || isEndOfLine(r) || l.atLineComment() {
+			return l.lexLineStatementEnd
+		}
+	}
+	return nil
+}
+
 func (l *Lexer) lexBlockEnd() lexFn {
 	l.accept("-")
 	l.Pos += len(l.Config.BlockEndString)
@@ -317,29 +522,21 @@ func (l *Lexer) lexBlockEnd() lexFn {
 
 func (l *Lexer) lexExpression() lexFn {
 	for {
+		// if this is the tag closing delimiter, but we are expecting the next char as a delimiter
+		// then skip marking this as the tag end.  This allows us to have, eg, '}}' as
+		// part of a literal inside a var block.
 		if !l.expectDelimiter(l.peek()) {
-			if l.hasPrefix(l.Config.VariableEndString) { // && l.expectDelimiter(l.peek()) {
-				return l.lexVariableEnd
-			}
-
-			// if this is the rightDelim, but we are expecting the next char as a delimiter
-			// then skip marking this as rightDelim.  This allows us to have, eg, '}}' as
-			// part of a literal inside a var block.
-			// if strings.HasPrefix(l.input[l.pos:], l.rightDelim) && !l.shouldExpectDelim(l.peek()) {
-			// 	l.pos += Pos(len(l.rightDelim))
-			// 	l.emitRight()
-			// 	return lexText
-			// }
-
-			if l.hasPrefix(l.Config.BlockEndString) {
-				return l.lexBlockEnd
+			if state := l.tagEndState(); state != nil {
+				return state
 			}
 		}
 
 		r := l.next()
 		// remaining := l.Input[l.Pos:]
 		switch {
-		case isSpace(r):
+		case r == rEOF:
+			return l.errorf("unexpected end of input, tag is not closed")
+		case isSpace(r), isEndOfLine(r):
 			return l.lexSpace
 		case isNumeric(r):
 			return l.lexNumber
@@ -362,19 +559,21 @@ func (l *Lexer) lexExpression() lexFn {
 		case '+':
 			l.emit(Add)
 		case '-':
-			if l.hasPrefix(l.Config.BlockEndString) {
-				l.backup()
-				return l.lexBlockEnd
-			} else if l.hasPrefix(l.Config.VariableEndString) {
+			if state := l.tagEndState(); state != nil {
 				l.backup()
-				return l.lexVariableEnd
+				return state
 			} else {
 				l.emit(Sub)
 			}
 		case '~':
 			l.emit(Tilde)
 		case ':':
-			l.emit(Colon)
+			if l.isLineStatementColon() {
+				// Optional trailing colon on line statements
+				l.ignore()
+			} else {
+				l.emit(Colon)
+			}
 		case '.':
 			l.emit(Dot)
 		case '%':
@@ -408,7 +607,8 @@ func (l *Lexer) lexExpression() lexFn {
 				l.emit(Ne)
 			} else {
 				// l.emit(Not)
-				l.errorf(`Unexpected "!"`)
+				l.backup()
+				return l.errorf(`Unexpected "!"`)
 			}
 		// case '&':
 		// 	if l.accept("&") {
@@ -432,18 +632,18 @@ func (l *Lexer) lexExpression() lexFn {
 			l.emit(Lbracket)
 			l.pushDelimiter(']')
 		case ')':
-			if !l.popDelimiter(')') {
-				return nil
+			if state, ok := l.popDelimiter(')'); !ok {
+				return state
 			}
 			l.emit(Rparen)
 		case '}':
-			if !l.popDelimiter('}') {
-				return nil
+			if state, ok := l.popDelimiter('}'); !ok {
+				return state
 			}
 			l.emit(Rbrace)
 		case ']':
-			if !l.popDelimiter(']') {
-				return nil
+			if state, ok := l.popDelimiter(']'); !ok {
+				return state
 			}
 			l.emit(Rbracket)
 		}
@@ -451,8 +651,29 @@ func (l *Lexer) lexExpression() lexFn {
 	return l.lexData
 }
 
+// isLineStatementColon returns true if the last consumed colon
+// is ending a line statement
+func (l *Lexer) isLineStatementColon() bool {
+	if l.tag != LinestatementBegin || len(l.delimiters) > 0 {
+		return false
+	}
+	for pos := l.Pos; pos < len(l.Input); pos++ {
+		r := rune(l.Input[pos])
+		if isEndOfLine(r) {
+			return true
+		}
+		if !isSpace(r) {
+			return l.Config.LineCommentPrefix != "" && strings.HasPrefix(l.Input[pos:], l.Config.LineCommentPrefix)
+		}
+	}
+	return true
+}
+
 func (l *Lexer) lexSpace() lexFn {
-	for isSpace(l.peek()) {
+	for r := l.peek(); isSpace(r) || isEndOfLine(r); r = l.peek() {
+		if l.tagEndState() != nil {
+			break
+		}
 		l.next()
 	}
 	l.emit(Whitespace)
@@ -488,7 +709,8 @@ func (l *Lexer) lexNumber() lexFn {
 			if tokType != Float {
 				tokType = Float
 			} else {
-				l.errorf("two dots in numeric token")
+				l.backup()
+				return l.errorf("two dots in numeric token")
 			}
 		case isAlphaNumeric(r) && tokType == Integer:
 			return l.lexIdentifier
@@ -512,6 +734,11 @@ func (l *Lexer) lexString() lexFn {
 	quote := l.next() // should be either ' or "
 	var prev rune
 	for r := l.next(); r != quote || prev == '\\'; r, prev = l.next(), r {
+		if r == rEOF {
+			// Report (and recover) from the opening quote
+			l.Pos = l.Start
+			return l.errorf("Unterminated string")
+		}
 	}
 	l.processAndEmit(String, unescape)
 	return l.lexExpression
@@ -3,6 +3,7 @@ package tokens_test
 import (
 	"testing"
 
+	"github.com/noirbizarre/gonja/config"
 	"github.com/noirbizarre/gonja/tokens"
 	"github.com/stretchr/testify/assert"
 )
@@ -214,6 +215,134 @@ var lexerCases = []struct {
 	}},
 }
 
+func latexConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.BlockStartString = `\BLOCK{`
+	cfg.BlockEndString = "}"
+	cfg.VariableStartString = `\VAR{`
+	cfg.VariableEndString = "}"
+	cfg.CommentStartString = `\#{`
+	cfg.CommentEndString = "}"
+	return cfg
+}
+
+func lineStatementConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.LineStatementPrefix = "#"
+	cfg.LineCommentPrefix = "##"
+	return cfg
+}
+
+func erbConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.BlockStartString = "<%"
+	cfg.BlockEndString = "%>"
+	cfg.VariableStartString = "<%="
+	cfg.VariableEndString = "%>"
+	cfg.CommentStartString = "<%#"
+	cfg.CommentEndString = "%>"
+	return cfg
+}
+
+var customDelimitersCases = []struct {
+	name     string
+	cfg      *config.Config
+	input    string
+	expected []tok
+}{
+	{"latex variable", latexConfig(), `\section{\VAR{ title }}`, []tok{
+		data(`\section{`),
+		tok{tokens.VariableBegin, `\VAR{`}, space, name("title"), space, tok{tokens.VariableEnd, "}"},
+		data("}"),
+		EOF,
+	}},
+	{"latex dict inside variable", latexConfig(), `\VAR{ {'a': b} }`, []tok{
+		tok{tokens.VariableBegin, `\VAR{`}, space,
+		lBrace, str("a"), tok{tokens.Colon, ":"}, space, name("b"), rBrace,
+		space, tok{tokens.VariableEnd, "}"},
+		EOF,
+	}},
+	{"latex blocks with trim control", latexConfig(), `\BLOCK{- if x -}yes\BLOCK{ endif }`, []tok{
+		tok{tokens.BlockBegin, `\BLOCK{-`}, space, name("if"), space, name("x"), space, tok{tokens.BlockEnd, "-}"},
+		data("yes"),
+		tok{tokens.BlockBegin, `\BLOCK{`}, space, name("endif"), space, tok{tokens.BlockEnd, "}"},
+		EOF,
+	}},
+	{"latex comment", latexConfig(), `a\#{ {% not a tag %} }b`, []tok{
+		data("a"),
+		tok{tokens.CommentBegin, `\#{`},
+		data(" {% not a tag %"),
+		tok{tokens.CommentEnd, "}"},
+		data(" }b"),
+		EOF,
+	}},
+	{"latex raw", latexConfig(), `\BLOCK{ raw }\VAR{ x }\BLOCK{- endraw }`, []tok{
+		tok{tokens.BlockBegin, `\BLOCK{`}, space, name("raw"), space, tok{tokens.BlockEnd, "}"},
+		data(`\VAR{ x }`),
+		tok{tokens.BlockBegin, `\BLOCK{-`}, space, name("endraw"), space, tok{tokens.BlockEnd, "}"},
+		EOF,
+	}},
+	{"overlapping delimiters", erbConfig(), "<% if a %><%= a %><%# comment %><% endif %>", []tok{
+		tok{tokens.BlockBegin, "<%"}, space, name("if"), space, name("a"), space, tok{tokens.BlockEnd, "%>"},
+		tok{tokens.VariableBegin, "<%="}, space, name("a"), space, tok{tokens.VariableEnd, "%>"},
+		tok{tokens.CommentBegin, "<%#"}, data(" comment "), tok{tokens.CommentEnd, "%>"},
+		tok{tokens.BlockBegin, "<%"}, space, name("endif"), space, tok{tokens.BlockEnd, "%>"},
+		EOF,
+	}},
+	{"default delimiters are ignored", erbConfig(), "{{ a }}{% b %}", []tok{
+		data("{{ a }}{% b %}"),
+		EOF,
+	}},
+	{"line statement", lineStatementConfig(), "  # for i in items:\n{{ i }}\n# endfor", []tok{
+		tok{tokens.LinestatementBegin, "#"}, space, name("for"), space, name("i"), space, name("in"), space, name("items"),
+		tok{tokens.LinestatementEnd, "\n"},
+		tok{tokens.VariableBegin, "{{"}, space, name("i"), space, tok{tokens.VariableEnd, "}}"},
+		data("\n"),
+		tok{tokens.LinestatementBegin, "#"}, space, name("endfor"),
+		tok{tokens.LinestatementEnd, ""},
+		EOF,
+	}},
+	{"line statement spanning lines", lineStatementConfig(), "# if (a\nor b)\n", []tok{
+		tok{tokens.LinestatementBegin, "#"}, space, name("if"), space,
+		tok{tokens.Lparen, "("}, name("a"), tok{tokens.Whitespace, "\n"}, name("or"), space, name("b"), tok{tokens.Rparen, ")"},
+		tok{tokens.LinestatementEnd, "\n"},
+		EOF,
+	}},
+	{"line comment", lineStatementConfig(), "a ## comment\nb", []tok{
+		data("a"),
+		tok{tokens.LinecommentBegin, "##"}, tok{tokens.Linecomment, " comment"}, tok{tokens.LinecommentEnd, ""},
+		data("\nb"),
+		EOF,
+	}},
+	{"line statement prefix inside data", lineStatementConfig(), "a # b", []tok{
+		data("a # b"),
+		EOF,
+	}},
+	{"comment with trim control", config.DefaultConfig, "{#- comment -#}", []tok{
+		tok{tokens.CommentBegin, "{#-"},
+		data(" comment "),
+		tok{tokens.CommentEnd, "-#}"},
+		EOF,
+	}},
+}
+
+func TestLexerCustomDelimiters(t *testing.T) {
+	for _, lc := range customDelimitersCases {
+		test := lc
+		t.Run(test.name, func(t *testing.T) {
+			lexer := tokens.NewLexerWithConfig(test.input, test.cfg)
+			go lexer.Run()
+			toks := tokenSlice(lexer.Tokens)
+
+			actual := []tok{}
+			for _, token := range toks {
+				actual = append(actual, tok{token.Type, token.Val})
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
 func tokenSlice(c chan *tokens.Token) []*tokens.Token {
 	toks := []*tokens.Token{}
 	for token := range c {
@@ -302,6 +431,20 @@ func TestStreamSlice(t *testing.T) {
 	}
 }
 
+func TestLexRecover(t *testing.T) {
+	stream := tokens.LexRecover("{{ a ! b }} ok {{ (x]) }}{% if 'abc %}skipped{{ y }}", config.DefaultConfig)
+	expected := []tok{
+		varBegin, name("a"), error(`Unexpected "!"`),
+		varBegin, lParen, name("x"), error(`Unbalanced delimiters, expected ")", got "]"`),
+		blockBegin, name("if"), error("Unterminated string"),
+		varBegin, name("y"), varEnd,
+	}
+
+	actual := streamResult(stream)
+
+	assert.Equal(t, expected, actual)
+}
+
 const positionsCase = `Hello
 {#
     Multiline comment
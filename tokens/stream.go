@@ -10,6 +10,7 @@ type Stream struct {
 	backup   *Token
 	buffer   []*Token
 	tokens   []*Token
+	recover  bool // Whether error tokens are part of the stream instead of ending it
 }
 
 type TokenIterator interface {
@@ -66,11 +67,15 @@ func NewStream(input interface{}) *Stream {
 	default:
 		panic(fmt.Sprintf(`Unsupported stream input type "%T"`, t))
 	}
+	return newStream(it, false)
+}
 
+func newStream(it TokenIterator, recover bool) *Stream {
 	s := &Stream{
-		it:     it,
-		buffer: []*Token{},
-		tokens: []*Token{},
+		it:      it,
+		buffer:  []*Token{},
+		tokens:  []*Token{},
+		recover: recover,
 	}
 	s.init()
 	return s
@@ -120,8 +125,10 @@ func (s *Stream) IsError() bool {
 	return s.current.Type == Error
 }
 
+// End returns true if there is no more token to consume.
+// Error tokens end the stream unless it is lexed in recovery mode.
 func (s *Stream) End() bool {
-	return s.EOF() || s.IsError()
+	return s.EOF() || (s.IsError() && !s.recover)
 }
 
 func (s *Stream) Peek() *Token {
@@ -0,0 +1,200 @@
+package gonja_test
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	"github.com/noirbizarre/gonja/exec"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+type undefinedExpected struct {
+	out string
+	err string // The expected error message, if any
+}
+
+var undefinedCases = []struct {
+	name      string
+	source    string
+	base      undefinedExpected
+	strict    undefinedExpected
+	chainable undefinedExpected
+	debug     undefinedExpected
+}{
+	{"print", "{{ missing }}",
+		undefinedExpected{"", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"", ""},
+		undefinedExpected{"{{ missing }}", ""},
+	},
+	{"missing attribute", "{{ user.missing }}",
+		undefinedExpected{"", ""},
+		undefinedExpected{"", "'user.missing' is undefined (Line: 1 Col: 8)"},
+		undefinedExpected{"", ""},
+		undefinedExpected{"{{ user.missing }}", ""},
+	},
+	{"missing item", "{{ items[5] }}",
+		undefinedExpected{"", ""},
+		undefinedExpected{"", "'items[5]' is undefined (Line: 1 Col: 9)"},
+		undefinedExpected{"", ""},
+		undefinedExpected{"{{ items[5] }}", ""},
+	},
+	{"attribute of undefined", "{{ missing.attr.sub }}",
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+	},
+	{"iteration", "{% for item in missing %}{{ item }}{% else %}empty{% endfor %}",
+		undefinedExpected{"empty", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 16)"},
+		undefinedExpected{"empty", ""},
+		undefinedExpected{"empty", ""},
+	},
+	{"condition", "{% if missing %}yes{% else %}no{% endif %}",
+		undefinedExpected{"no", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 7)"},
+		undefinedExpected{"no", ""},
+		undefinedExpected{"no", ""},
+	},
+	{"equality", "{{ missing == 1 }}",
+		undefinedExpected{"False", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"False", ""},
+		undefinedExpected{"False", ""},
+	},
+	{"comparison", "{{ missing < 1 }}",
+		undefinedExpected{"True", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"True", ""},
+	},
+	{"comparison condition", "{% if missing > 1 %}yes{% else %}no{% endif %}",
+		undefinedExpected{"no", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 7)"},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 7)"},
+		undefinedExpected{"no", ""},
+	},
+	{"arithmetic", "{{ 1 + missing }}",
+		undefinedExpected{"1", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 8)"},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 8)"},
+		undefinedExpected{"1", ""},
+	},
+	{"concatenation", "{{ missing ~ 'a' }}",
+		undefinedExpected{"a", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"a", ""},
+		undefinedExpected{"a", ""},
+	},
+	{"filters", "{{ missing|upper }}{{ missing|string }}{{ missing|length }}",
+		undefinedExpected{"0", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 4)"},
+		undefinedExpected{"0", ""},
+		undefinedExpected{"0", ""},
+	},
+	{"inline if", "{{ 'yes' if missing }}",
+		undefinedExpected{"", ""},
+		undefinedExpected{"", "'missing' is undefined (Line: 1 Col: 13)"},
+		undefinedExpected{"", ""},
+		undefinedExpected{"{{ 'yes' if missing }}", ""},
+	},
+	{"tests", "{{ missing is defined }} {{ user.missing is undefined }} {{ user.name is defined }}",
+		undefinedExpected{"False True True", ""},
+		undefinedExpected{"False True True", ""},
+		undefinedExpected{"False True True", ""},
+		undefinedExpected{"False True True", ""},
+	},
+	{"default filter", "{{ missing|default('fallback') }}",
+		undefinedExpected{"fallback", ""},
+		undefinedExpected{"fallback", ""},
+		undefinedExpected{"fallback", ""},
+		undefinedExpected{"fallback", ""},
+	},
+	{"default filter alias", "{{ missing|d('fallback') }}",
+		undefinedExpected{"fallback", ""},
+		undefinedExpected{"fallback", ""},
+		undefinedExpected{"fallback", ""},
+		undefinedExpected{"fallback", ""},
+	},
+}
+
+func assertUndefined(t *testing.T, env *gonja.Environment, source string, expected undefinedExpected) {
+	ctx := map[string]interface{}{
+		"user":  map[string]interface{}{"name": "john"},
+		"items": []int{1, 2},
+	}
+	out, err := tu.Render(t, env, source, ctx)
+	if expected.err == "" {
+		assert.Nil(t, err)
+		assert.Equal(t, expected.out, out)
+	} else if assert.NotNil(t, err) {
+		assert.True(t, exec.IsUndefinedError(err), "expected an UndefinedError, got %v", err)
+		te, ok := exec.AsTemplateError(err)
+		if assert.True(t, ok) {
+			assert.Contains(t, te.Message, expected.err)
+		}
+	}
+}
+
+func TestUndefined(t *testing.T) {
+	policies := []struct {
+		name     string
+		policy   exec.UndefinedPolicy
+		expected func(int) undefinedExpected
+	}{
+		{"default", nil, func(idx int) undefinedExpected { return undefinedCases[idx].base }},
+		{"strict", exec.StrictUndefined{}, func(idx int) undefinedExpected { return undefinedCases[idx].strict }},
+		{"chainable", exec.ChainableUndefined{}, func(idx int) undefinedExpected { return undefinedCases[idx].chainable }},
+		{"debug", exec.DebugUndefined{}, func(idx int) undefinedExpected { return undefinedCases[idx].debug }},
+	}
+	for _, p := range policies {
+		policy := p
+		t.Run(policy.name, func(t *testing.T) {
+			env := tu.NewEnv(nil)
+			env.Undefined = policy.policy
+			for idx, uc := range undefinedCases {
+				test := uc
+				expected := policy.expected(idx)
+				t.Run(test.name, func(t *testing.T) {
+					assertUndefined(t, env, test.source, expected)
+				})
+			}
+		})
+	}
+}
+
+func TestUndefinedTemplateError(t *testing.T) {
+	assert := assert.New(t)
+	env := tu.NewEnv(nil)
+	env.Undefined = exec.StrictUndefined{}
+	_, err := tu.Render(t, env, "<p>\n  {{ user.nmae }}\n</p>", map[string]interface{}{"user": map[string]interface{}{"name": "john"}})
+	te, ok := exec.AsTemplateError(err)
+	if assert.True(ok, "expected a TemplateError, got %v", err) {
+		assert.Equal(exec.RenderPhase, te.Phase)
+		assert.Equal(2, te.Line)
+		assert.Equal(10, te.Column)
+		assert.Contains(te.Message, "'user.nmae' is undefined")
+	}
+}
+
+func TestLoggingUndefined(t *testing.T) {
+	assert := assert.New(t)
+	logger, hook := logtest.NewNullLogger()
+	env := tu.NewEnv(nil)
+	env.Undefined = exec.NewLoggingUndefined(nil, logger)
+	_, err := tu.Render(t, env, "{{ missing }}{{ missing.attr }}", nil)
+	assert.True(exec.IsUndefinedError(err))
+	if assert.Len(hook.AllEntries(), 2) {
+		warning := hook.AllEntries()[0]
+		assert.Equal(logrus.WarnLevel, warning.Level)
+		assert.Equal("Template variable warning: 'missing' is undefined", warning.Message)
+		assert.Equal("missing", warning.Data["name"])
+		assert.Equal(1, warning.Data["line"])
+		assert.Equal(logrus.ErrorLevel, hook.LastEntry().Level)
+	}
+}
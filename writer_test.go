@@ -0,0 +1,124 @@
+package gonja_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/noirbizarre/gonja"
+	tu "github.com/noirbizarre/gonja/testutils"
+)
+
+// chunkWriter records every write it receives
+type chunkWriter struct {
+	chunks []string
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.chunks = append(w.chunks, string(p))
+	return len(p), nil
+}
+
+func (w *chunkWriter) String() string {
+	return strings.Join(w.chunks, "")
+}
+
+type failingWriter struct{}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+var writerCtx = map[string]interface{}{
+	"items": []int{1, 2, 3},
+	"fail": func() (string, error) {
+		return "", errors.New("failure")
+	},
+}
+
+func TestExecuteWriterStreams(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("{% for i in items %}{{ i }}\n{% endfor %}")
+	if !assert.Nil(err) {
+		return
+	}
+	var w chunkWriter
+	err = tpl.ExecuteWriter(writerCtx, &w)
+	if assert.Nil(err) {
+		assert.Equal("1\n2\n3", w.String())
+		assert.True(len(w.chunks) > 1, "output should be written in several chunks")
+	}
+}
+
+func TestExecuteWriterTrailingNewline(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		env := tu.NewEnv(nil)
+		env.KeepTrailingNewline = keep
+		tpl, err := env.FromString("{% for i in items %}{{ i }}\r\n{% endfor %}")
+		if !assert.Nil(t, err) {
+			return
+		}
+		var w chunkWriter
+		err = tpl.ExecuteWriter(writerCtx, &w)
+		if assert.Nil(t, err) {
+			expected := "1\n2\n3"
+			if keep {
+				expected += "\n"
+			}
+			assert.Equal(t, expected, w.String())
+			out, _ := tpl.Execute(writerCtx)
+			assert.Equal(t, out, w.String(), "streamed and buffered outputs should match")
+		}
+	}
+}
+
+func TestExecuteWriterPartialOutputOnError(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("before{% if true %}{{ fail() }}{% endif %}")
+	if !assert.Nil(err) {
+		return
+	}
+	var w bytes.Buffer
+	err = tpl.ExecuteWriter(writerCtx, &w)
+	assert.NotNil(err)
+	assert.Equal("before", w.String())
+}
+
+func TestExecuteWriterBufferedWritesNothingOnError(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("before{% if true %}{{ fail() }}{% endif %}")
+	if !assert.Nil(err) {
+		return
+	}
+	var w bytes.Buffer
+	err = tpl.ExecuteWriterBuffered(writerCtx, &w)
+	assert.NotNil(err)
+	assert.Equal("", w.String())
+}
+
+func TestExecuteWriterBuffered(t *testing.T) {
+	assert := assert.New(t)
+	tpl, err := gonja.FromString("{% for i in items %}{{ i }}{% endfor %}")
+	if !assert.Nil(err) {
+		return
+	}
+	var w chunkWriter
+	err = tpl.ExecuteWriterBuffered(writerCtx, &w)
+	if assert.Nil(err) {
+		assert.Equal([]string{"123"}, w.chunks)
+	}
+}
+
+func TestExecuteWriterError(t *testing.T) {
+	tpl, err := gonja.FromString("{% for i in items %}{{ i }}{% endfor %}")
+	if !assert.Nil(t, err) {
+		return
+	}
+	err = tpl.ExecuteWriter(writerCtx, &failingWriter{})
+	assert.NotNil(t, err)
+	err = tpl.ExecuteWriterBuffered(writerCtx, &failingWriter{})
+	assert.NotNil(t, err)
+}